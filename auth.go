@@ -0,0 +1,46 @@
+package emi_transport
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// 通过查询参数传递 access token 时使用的参数名
+const accessTokenQueryKey = "access_token"
+
+// access token 的携带方式
+type AuthMode int
+
+const (
+	AuthBearer AuthMode = 0 + iota // Authorization: Bearer <token>（默认）
+	AuthScheme                     // Authorization: <scheme> <token>，scheme 为空时直接使用 token
+	AuthQuery                      // 查询参数 ?access_token=<token>
+	AuthNone                       // 不携带 token
+)
+
+type authConfig struct {
+	mode   AuthMode
+	scheme string
+}
+
+// 把 token 按照配置的方式写入请求头或请求地址
+func (a authConfig) apply(header http.Header, u *url.URL, token string) {
+	if token == "" {
+		return
+	}
+
+	switch a.mode {
+	case AuthBearer:
+		header.Set("Authorization", "Bearer "+token)
+	case AuthScheme:
+		if a.scheme == "" {
+			header.Set("Authorization", token)
+		} else {
+			header.Set("Authorization", a.scheme+" "+token)
+		}
+	case AuthQuery:
+		query := u.Query()
+		query.Set(accessTokenQueryKey, token)
+		u.RawQuery = query.Encode()
+	}
+}
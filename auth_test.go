@@ -0,0 +1,53 @@
+package emi_transport
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestAuthConfigApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		config authConfig
+		header string
+		query  string
+	}{
+		{"bearer", authConfig{mode: AuthBearer}, "Bearer token", ""},
+		{"scheme", authConfig{mode: AuthScheme, scheme: "Token"}, "Token token", ""},
+		{"empty scheme", authConfig{mode: AuthScheme}, "token", ""},
+		{"query", authConfig{mode: AuthQuery}, "", "token"},
+		{"none", authConfig{mode: AuthNone}, "", ""},
+	}
+
+	for _, test := range tests {
+		header := http.Header{}
+		u, _ := url.Parse("http://127.0.0.1:3000/api")
+		test.config.apply(header, u, "token")
+
+		if got := header.Get("Authorization"); got != test.header {
+			t.Errorf("%s: Authorization = %q, want %q", test.name, got, test.header)
+		}
+		if got := u.Query().Get(accessTokenQueryKey); got != test.query {
+			t.Errorf("%s: access_token = %q, want %q", test.name, got, test.query)
+		}
+	}
+}
+
+func TestPostAuthQuery(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get(accessTokenQueryKey); got != "secret-token" {
+			t.Errorf("access_token = %q, want secret-token", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization = %q, want empty", got)
+		}
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+	h.SetAuthMode(AuthQuery, "")
+
+	if err := h.Post(context.Background(), "x", nil, nil); err != nil {
+		t.Errorf("Post returned %v", err)
+	}
+}
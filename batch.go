@@ -0,0 +1,150 @@
+package emi_transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	emi_core "github.com/aK1r4z/emi-core"
+)
+
+// 批量请求的默认并发数
+const defaultBatchConcurrency = 8
+
+var ErrInvalidRecallTarget = errors.New("recall target must set exactly one of Private and Group")
+
+// 批量请求中部分请求失败
+type BatchError struct {
+	Errors []error // 与请求一一对应，成功的请求为 nil
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e.Errors {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed += 1
+		}
+	}
+	return fmt.Sprintf("%d of %d batch requests failed, first error: %v", failed, len(e.Errors), first)
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// 设置批量请求的最大并发数，不大于 0 时使用默认值
+func (h *HttpClient) SetBatchConcurrency(concurrency int) {
+	h.batchConcurrency = concurrency
+}
+
+// 以受限的并发数执行 n 个请求，所有请求都会被执行，有失败时返回 *BatchError
+func (h *HttpClient) runBatch(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	concurrency := h.batchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	errs := make([]error, n)
+
+	wg := sync.WaitGroup{}
+	semaphore := make(chan struct{}, concurrency)
+
+	for i := range n {
+		semaphore <- struct{}{}
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			errs[i] = fn(ctx, i)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return &BatchError{Errors: errs}
+		}
+	}
+
+	return nil
+}
+
+// 撤回目标，Private 与 Group 须且仅须设置其一
+type RecallTarget struct {
+	Private *emi_core.RecallPrivateMessageRequest
+	Group   *emi_core.RecallGroupMessageRequest
+}
+
+// 并发撤回多条私聊/群聊消息
+//
+// 每个目标都会被尝试，部分失败时返回 *BatchError，其中 Errors[i] 对应 targets[i]。
+func (h *HttpClient) RecallMessages(ctx context.Context, targets []RecallTarget) error {
+	return h.runBatch(ctx, len(targets), func(ctx context.Context, i int) error {
+		target := targets[i]
+
+		switch {
+		case target.Private != nil && target.Group == nil:
+			_, err := h.RecallPrivateMessage(ctx, *target.Private)
+			return err
+		case target.Group != nil && target.Private == nil:
+			_, err := h.RecallGroupMessage(ctx, *target.Group)
+			return err
+		default:
+			return ErrInvalidRecallTarget
+		}
+	})
+}
+
+// 批量获取群成员信息中单个成员的结果
+type GroupMemberResult struct {
+	Member *emi_core.GetGroupMemberInfoResponse
+	Err    error
+}
+
+// 并发获取同一群内多个成员的信息
+//
+// 重复的用户 ID 只会请求一次，返回以用户 ID 为键的结果，每个成员的错误单独记录在结果中。
+func (h *HttpClient) GetGroupMembers(ctx context.Context, groupID int64, userIDs []int64) map[int64]GroupMemberResult {
+	unique := make([]int64, 0, len(userIDs))
+	seen := make(map[int64]struct{}, len(userIDs))
+	for _, userID := range userIDs {
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		unique = append(unique, userID)
+	}
+
+	members := make([]GroupMemberResult, len(unique))
+	h.runBatch(ctx, len(unique), func(ctx context.Context, i int) error {
+		member, err := h.GetGroupMemberInfo(ctx, emi_core.GetGroupMemberInfoRequest{
+			GroupID: groupID,
+			UserID:  unique[i],
+		})
+		members[i] = GroupMemberResult{
+			Member: member,
+			Err:    err,
+		}
+		return err
+	})
+
+	results := make(map[int64]GroupMemberResult, len(unique))
+	for i, userID := range unique {
+		results[userID] = members[i]
+	}
+	return results
+}
@@ -0,0 +1,132 @@
+package emi_transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	emi_core "github.com/aK1r4z/emi-core"
+)
+
+func TestRunBatchLimitsConcurrency(t *testing.T) {
+	logger, _ := newTestLogger()
+	h := NewHttpClient(logger, "http://127.0.0.1", "")
+	h.SetBatchConcurrency(2)
+
+	running, peak, calls := atomic.Int32{}, atomic.Int32{}, atomic.Int32{}
+	err := h.runBatch(context.Background(), 10, func(ctx context.Context, i int) error {
+		current := running.Add(1)
+		defer running.Add(-1)
+
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+		calls.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("runBatch returned %v", err)
+	}
+	if calls.Load() != 10 {
+		t.Errorf("calls = %d, want 10", calls.Load())
+	}
+	if peak.Load() > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak.Load())
+	}
+}
+
+func TestRunBatchError(t *testing.T) {
+	logger, _ := newTestLogger()
+	h := NewHttpClient(logger, "http://127.0.0.1", "")
+
+	err := h.runBatch(context.Background(), 4, func(ctx context.Context, i int) error {
+		if i%2 == 1 {
+			return errTest
+		}
+		return nil
+	})
+
+	batchErr := &BatchError{}
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("runBatch returned %T %v, want *BatchError", err, err)
+	}
+	for i, err := range batchErr.Errors {
+		if (i%2 == 1) != (err != nil) {
+			t.Errorf("Errors[%d] = %v", i, err)
+		}
+	}
+	if !errors.Is(err, errTest) {
+		t.Errorf("errors.Is(err, errTest) = false")
+	}
+}
+
+func TestRecallMessages(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+
+	err := h.RecallMessages(context.Background(), []RecallTarget{
+		{Private: &emi_core.RecallPrivateMessageRequest{}},
+		{},
+		{Private: &emi_core.RecallPrivateMessageRequest{}, Group: &emi_core.RecallGroupMessageRequest{}},
+		{Group: &emi_core.RecallGroupMessageRequest{}},
+	})
+
+	batchErr := &BatchError{}
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("RecallMessages returned %T %v, want *BatchError", err, err)
+	}
+	for i, want := range []error{nil, ErrInvalidRecallTarget, ErrInvalidRecallTarget, nil} {
+		if batchErr.Errors[i] != want {
+			t.Errorf("Errors[%d] = %v, want %v", i, batchErr.Errors[i], want)
+		}
+	}
+}
+
+func TestGetGroupMembersDeduplicates(t *testing.T) {
+	requested := sync.Map{}
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		request := emi_core.GetGroupMemberInfoRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		count, _ := requested.LoadOrStore(request.UserID, &atomic.Int32{})
+		count.(*atomic.Int32).Add(1)
+
+		if request.UserID == 2 {
+			json.NewEncoder(w).Encode(HttpResult{Status: "failed", Code: 1404, Message: "member not found"})
+			return
+		}
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+
+	members := h.GetGroupMembers(context.Background(), 100, []int64{1, 2, 1})
+
+	for _, userID := range []int64{1, 2} {
+		count, ok := requested.Load(userID)
+		if !ok || count.(*atomic.Int32).Load() != 1 {
+			t.Errorf("member %d was not requested exactly once", userID)
+		}
+	}
+	if len(members) != 2 {
+		t.Fatalf("GetGroupMembers returned %d results, want 2", len(members))
+	}
+
+	if result := members[1]; result.Err != nil || result.Member == nil {
+		t.Errorf("member 1: Member = %v, Err = %v, want a member", result.Member, result.Err)
+	}
+	apiError := &APIError{}
+	if result := members[2]; !errors.As(result.Err, &apiError) || apiError.Code != 1404 || result.Member != nil {
+		t.Errorf("member 2: Member = %v, Err = %v, want *APIError with code 1404", result.Member, result.Err)
+	}
+}
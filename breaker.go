@@ -0,0 +1,115 @@
+package emi_transport
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = 0 + iota // 正常放行请求
+	CircuitOpen                             // 直接拒绝请求
+	CircuitHalfOpen                         // 放行一个探测请求
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "CLOSED"
+	case CircuitOpen:
+		return "OPEN"
+	case CircuitHalfOpen:
+		return "HALF_OPEN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// 熔断器
+//
+// 连续失败 failureThreshold 次后熔断器打开，在 cooldown 时间内直接拒绝请求；
+// 冷却结束后进入半开状态，放行一个探测请求，探测成功则关闭，失败则重新打开。
+type CircuitBreaker struct {
+	sync.Mutex
+
+	failureThreshold int
+	cooldown         time.Duration
+
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(failureThreshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		failureThreshold: max(failureThreshold, 1),
+		cooldown:         cooldown,
+
+		state: CircuitClosed,
+	}
+}
+
+// 当前状态
+func (b *CircuitBreaker) State() CircuitState {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.state
+}
+
+// 判断是否放行请求
+func (b *CircuitBreaker) allow(now time.Time) error {
+	b.Lock()
+	defer b.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// 记录请求结果
+func (b *CircuitBreaker) record(now time.Time, success bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.probing = false
+
+	if success {
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures += 1
+	if b.state == CircuitHalfOpen || b.failures >= b.failureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = now
+	}
+}
+
+// 请求被调用方取消，不计入结果
+func (b *CircuitBreaker) release() {
+	b.Lock()
+	defer b.Unlock()
+
+	b.probing = false
+}
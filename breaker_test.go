@@ -0,0 +1,112 @@
+package emi_transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	breaker := NewCircuitBreaker(2, time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 2; i++ {
+		if err := breaker.allow(now); err != nil {
+			t.Fatalf("allow returned %v while closed", err)
+		}
+		breaker.record(now, false)
+	}
+	if state := breaker.State(); state != CircuitOpen {
+		t.Fatalf("state = %s after 2 failures, want OPEN", state)
+	}
+
+	if err := breaker.allow(now.Add(30 * time.Second)); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow returned %v during cooldown, want ErrCircuitOpen", err)
+	}
+
+	// 冷却结束后放行一个探测请求，其余请求仍被拒绝
+	probeAt := now.Add(time.Minute)
+	if err := breaker.allow(probeAt); err != nil {
+		t.Fatalf("allow returned %v after cooldown", err)
+	}
+	if state := breaker.State(); state != CircuitHalfOpen {
+		t.Errorf("state = %s after cooldown, want HALF_OPEN", state)
+	}
+	if err := breaker.allow(probeAt); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow returned %v while probing, want ErrCircuitOpen", err)
+	}
+
+	// 探测失败重新打开
+	breaker.record(probeAt, false)
+	if state := breaker.State(); state != CircuitOpen {
+		t.Errorf("state = %s after failed probe, want OPEN", state)
+	}
+
+	// 探测成功后关闭
+	probeAt = probeAt.Add(time.Minute)
+	if err := breaker.allow(probeAt); err != nil {
+		t.Fatalf("allow returned %v after second cooldown", err)
+	}
+	breaker.record(probeAt, true)
+	if state := breaker.State(); state != CircuitClosed {
+		t.Errorf("state = %s after successful probe, want CLOSED", state)
+	}
+}
+
+func TestCircuitBreakerReleaseDoesNotCount(t *testing.T) {
+	breaker := NewCircuitBreaker(1, time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	breaker.record(now, false)
+	if err := breaker.allow(now.Add(time.Minute)); err != nil {
+		t.Fatalf("allow returned %v after cooldown", err)
+	}
+	breaker.release()
+
+	if state := breaker.State(); state != CircuitHalfOpen {
+		t.Errorf("state = %s after release, want HALF_OPEN", state)
+	}
+	if err := breaker.allow(now.Add(time.Minute)); err != nil {
+		t.Errorf("allow returned %v after release, want a new probe", err)
+	}
+}
+
+func TestCircuitBreakerOpensOnTransportErrors(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	h.SetCircuitBreaker(NewCircuitBreaker(2, time.Hour))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		h.PostOnce(ctx, "x", nil, nil)
+	}
+
+	if err := h.PostOnce(ctx, "x", nil, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("PostOnce returned %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresAPIErrors(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(HttpResult{Status: "failed", Code: 1403, Message: "permission denied"})
+	})
+	breaker := NewCircuitBreaker(2, time.Hour)
+	h.SetCircuitBreaker(breaker)
+
+	for i := 0; i < 5; i++ {
+		err := h.Post(context.Background(), "x", nil, &map[string]any{})
+
+		apiError := &APIError{}
+		if !errors.As(err, &apiError) {
+			t.Fatalf("call %d: Post returned %v, want *APIError", i, err)
+		}
+	}
+
+	if state := breaker.State(); state != CircuitClosed {
+		t.Errorf("state = %s after API errors, want CLOSED", state)
+	}
+}
@@ -0,0 +1,20 @@
+package emi_transport
+
+import "time"
+
+// 时钟，可在测试中替换为可控的实现
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// 使用系统时间的时钟
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,77 @@
+package emi_transport
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// 测试用的时钟，After 立即返回并把当前时间向前推进 d
+type fakeClock struct {
+	sync.Mutex
+
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now: time.Unix(1700000000, 0),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// 调用 After 时传入的全部时长
+func (c *fakeClock) Sleeps() []time.Duration {
+	c.Lock()
+	defer c.Unlock()
+
+	return append([]time.Duration(nil), c.sleeps...)
+}
+
+// 可并发写入的缓冲区，用于收集日志
+type syncBuffer struct {
+	sync.Mutex
+
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.buffer.String()
+}
+
+// 输出到缓冲区的日志器
+func newTestLogger() (*TinyLogger, *syncBuffer) {
+	buffer := &syncBuffer{}
+	logger := NewTinyLogger("test")
+	logger.SetWriter(buffer)
+	return logger, buffer
+}
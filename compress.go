@@ -0,0 +1,34 @@
+package emi_transport
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+// 设置请求体压缩阈值
+//
+// 请求体不小于 threshold 字节时使用 gzip 压缩并设置 Content-Encoding: gzip，
+// 不大于 0 时不压缩（默认）。协议端以 415 拒绝压缩的请求体时，客户端会自动停用压缩。
+func (h *HttpClient) SetRequestCompression(threshold int) {
+	h.compressThreshold = threshold
+	h.compressRejected.Store(false)
+}
+
+// 判断请求体是否需要压缩
+func (h *HttpClient) shouldCompress(body []byte) bool {
+	return h.compressThreshold > 0 && len(body) >= h.compressThreshold && !h.compressRejected.Load()
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	buffer := bytes.Buffer{}
+
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,71 @@
+package emi_transport
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPostCompressesLargeBodies(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Fatalf("invalid gzip body: %v", err)
+			}
+			body = reader
+		}
+		raw, _ := io.ReadAll(body)
+		writeResult(w, "ok", 0, map[string]any{
+			"encoding": r.Header.Get("Content-Encoding"),
+			"length":   len(raw),
+		})
+	})
+	h.SetRequestCompression(100)
+
+	tests := []struct {
+		name     string
+		request  map[string]string
+		encoding string
+	}{
+		{"small", map[string]string{"a": "b"}, ""},
+		{"large", map[string]string{"a": strings.Repeat("b", 1000)}, "gzip"},
+	}
+	for _, test := range tests {
+		out := map[string]any{}
+		if err := h.Post(context.Background(), "x", test.request, &out); err != nil {
+			t.Fatalf("%s: Post returned %v", test.name, err)
+		}
+		if out["encoding"] != test.encoding {
+			t.Errorf("%s: Content-Encoding = %v, want %q", test.name, out["encoding"], test.encoding)
+		}
+	}
+}
+
+func TestPostDisablesCompressionOnUnsupportedMediaType(t *testing.T) {
+	compressed := atomic.Int32{}
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			compressed.Add(1)
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+	h.SetRequestCompression(10)
+
+	request := map[string]string{"a": strings.Repeat("b", 100)}
+	for i := 0; i < 2; i++ {
+		if err := h.Post(context.Background(), "x", request, nil); err != nil {
+			t.Fatalf("call %d: Post returned %v", i, err)
+		}
+	}
+	if compressed.Load() != 1 {
+		t.Errorf("compressed requests = %d, want 1", compressed.Load())
+	}
+}
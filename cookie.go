@@ -0,0 +1,46 @@
+package emi_transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	emi_core "github.com/aK1r4z/emi-core"
+)
+
+// 把 GetCookies 返回的 "k1=v1; k2=v2" 形式的字符串解析为 Cookie 列表
+func ParseCookies(cookies string) ([]*http.Cookie, error) {
+	if cookies == "" {
+		return []*http.Cookie{}, nil
+	}
+
+	parsed, err := http.ParseCookie(cookies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cookies: %w", err)
+	}
+	return parsed, nil
+}
+
+// 获取 domain 的 Cookies 并解析为 Cookie 列表
+func (h *HttpClient) GetHTTPCookies(ctx context.Context, domain string) ([]*http.Cookie, error) {
+	resp, err := h.GetCookies(ctx, emi_core.GetCookiesRequest{
+		Domain: domain,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCookies(resp.Cookies)
+}
+
+// 获取 domain 的 Cookies 并写入 jar，之后可以通过使用该 jar 的 http.Client 访问 https://domain
+func (h *HttpClient) FillCookieJar(ctx context.Context, jar http.CookieJar, domain string) error {
+	cookies, err := h.GetHTTPCookies(ctx, domain)
+	if err != nil {
+		return err
+	}
+
+	jar.SetCookies(&url.URL{Scheme: "https", Host: domain, Path: "/"}, cookies)
+	return nil
+}
@@ -0,0 +1,29 @@
+package emi_transport
+
+import (
+	"testing"
+)
+
+func TestParseCookies(t *testing.T) {
+	cookies, err := ParseCookies("uin=o10001; skey=@AbCdEfGh; p_skey=x=y")
+	if err != nil {
+		t.Fatalf("ParseCookies returned %v", err)
+	}
+
+	want := map[string]string{"uin": "o10001", "skey": "@AbCdEfGh", "p_skey": "x=y"}
+	if len(cookies) != len(want) {
+		t.Fatalf("ParseCookies returned %d cookies, want %d", len(cookies), len(want))
+	}
+	for _, cookie := range cookies {
+		if want[cookie.Name] != cookie.Value {
+			t.Errorf("cookie %s = %q, want %q", cookie.Name, cookie.Value, want[cookie.Name])
+		}
+	}
+}
+
+func TestParseCookiesEmpty(t *testing.T) {
+	cookies, err := ParseCookies("")
+	if err != nil || cookies == nil || len(cookies) != 0 {
+		t.Errorf("ParseCookies(\"\") = %v, %v, want an empty list", cookies, err)
+	}
+}
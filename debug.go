@@ -0,0 +1,109 @@
+package emi_transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// 日志中用于替换敏感信息的占位符
+const redacted = "[REDACTED]"
+
+// 发送一次请求，并把完整的请求与响应以 Info 等级写入日志，返回原始响应体
+//
+// 用于排查实际发送的内容：不重试、不压缩、不经过熔断器与 dry-run，
+// 但会执行请求与响应拦截器。日志中的 access token 会被遮盖。
+func (h *HttpClient) Debug(ctx context.Context, endpoint string, request any) ([]byte, error) {
+	if h.closed.Load() {
+		return nil, ErrClosed
+	}
+
+	urlPath, err := url.JoinPath(h.restGateway, h.apiPrefix, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join URL path: %w", err)
+	}
+
+	jsonBytes := []byte{}
+	if request != nil {
+		jsonBytes, err = json.Marshal(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, urlPath, bytes.NewReader(jsonBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	h.auth.apply(req.Header, req.URL, h.accessToken)
+
+	for _, interceptor := range h.requestInterceptors {
+		if err := interceptor(req); err != nil {
+			return nil, fmt.Errorf("request interceptor failed: %w", err)
+		}
+	}
+
+	h.logger.Infof("Debug request: POST %s", redactURL(req.URL))
+	for key, values := range redactHeader(req.Header) {
+		for _, value := range values {
+			h.logger.Infof("Debug request header: %s: %s", key, value)
+		}
+	}
+	h.logger.Infof("Debug request body: %s", string(jsonBytes))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	for _, interceptor := range h.responseInterceptors {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err := interceptor(resp); err != nil {
+			return body, fmt.Errorf("response interceptor failed: %w", err)
+		}
+	}
+
+	h.logger.Infof("Debug response status: %s", resp.Status)
+	for key, values := range redactHeader(resp.Header) {
+		for _, value := range values {
+			h.logger.Infof("Debug response header: %s: %s", key, value)
+		}
+	}
+	h.logger.Infof("Debug response body: %s", string(body))
+
+	return body, nil
+}
+
+// 复制请求头并遮盖其中的凭据
+func redactHeader(header http.Header) http.Header {
+	clone := header.Clone()
+	for _, key := range []string{"Authorization", "Cookie", "Set-Cookie"} {
+		if _, ok := clone[key]; ok {
+			clone.Set(key, redacted)
+		}
+	}
+	return clone
+}
+
+// 遮盖地址中的 access token
+func redactURL(u *url.URL) string {
+	clone := *u
+	query := clone.Query()
+	if query.Has(accessTokenQueryKey) {
+		query.Set(accessTokenQueryKey, redacted)
+		clone.RawQuery = query.Encode()
+	}
+	return clone.Redacted()
+}
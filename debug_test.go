@@ -0,0 +1,32 @@
+package emi_transport
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDebugDumpsRequestAndResponse(t *testing.T) {
+	h, logs := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":{"nickname":"emi"}}`))
+	})
+
+	body, err := h.Debug(context.Background(), "get_login_info", map[string]int{"user_id": 10001})
+	if err != nil {
+		t.Fatalf("Debug returned %v", err)
+	}
+	if !strings.Contains(string(body), `"nickname":"emi"`) {
+		t.Errorf("body = %s", body)
+	}
+
+	output := logs.String()
+	for _, want := range []string{`{"user_id":10001}`, `"nickname":"emi"`, "/get_login_info", "200 OK", "Authorization: " + redacted} {
+		if !strings.Contains(output, want) {
+			t.Errorf("dump does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "secret-token") {
+		t.Errorf("dump leaks the access token:\n%s", output)
+	}
+}
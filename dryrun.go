@@ -0,0 +1,90 @@
+package emi_transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// 记录下来的请求
+type RecordedRequest struct {
+	Endpoint string
+	Request  json.RawMessage
+}
+
+// 请求记录器，dry-run 模式下接收 Post 发出的请求
+type RequestRecorder interface {
+	Record(RecordedRequest)
+}
+
+// 在内存中保存请求的记录器
+type MemoryRecorder struct {
+	sync.Mutex
+
+	requests []RecordedRequest
+}
+
+func NewMemoryRecorder() *MemoryRecorder {
+	return &MemoryRecorder{}
+}
+
+func (r *MemoryRecorder) Record(request RecordedRequest) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.requests = append(r.requests, request)
+}
+
+// 已记录的全部请求
+func (r *MemoryRecorder) Requests() []RecordedRequest {
+	r.Lock()
+	defer r.Unlock()
+
+	return append([]RecordedRequest(nil), r.requests...)
+}
+
+type dryRun struct {
+	recorder RequestRecorder
+	fixtures map[string]json.RawMessage
+}
+
+// 开启 dry-run 模式
+//
+// 开启后 Post 不再发送网络请求，而是把请求交给 recorder（可为 nil），
+// 并以 fixtures 中对应接口的数据（即响应中的 data 字段）作为响应；没有对应数据时返回空响应。
+func (h *HttpClient) SetDryRun(recorder RequestRecorder, fixtures map[string]json.RawMessage) {
+	h.dryRun = &dryRun{
+		recorder: recorder,
+		fixtures: fixtures,
+	}
+}
+
+// 关闭 dry-run 模式
+func (h *HttpClient) DisableDryRun() {
+	h.dryRun = nil
+}
+
+func (d *dryRun) post(endpoint string, request any, response any) error {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if d.recorder != nil {
+		d.recorder.Record(RecordedRequest{
+			Endpoint: endpoint,
+			Request:  requestBytes,
+		})
+	}
+
+	data, ok := d.fixtures[endpoint]
+	if !ok || response == nil || len(data) == 0 {
+		return nil
+	}
+
+	if err := decodeJSON(data, response); err != nil {
+		return fmt.Errorf("failed to decode fixture: %w", err)
+	}
+
+	return nil
+}
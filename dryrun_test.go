@@ -0,0 +1,50 @@
+package emi_transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	emi_core "github.com/aK1r4z/emi-core"
+)
+
+func TestDryRunRecordsAndReplays(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent in dry-run mode")
+	})
+
+	recorder := NewMemoryRecorder()
+	h.SetDryRun(recorder, map[string]json.RawMessage{
+		"get_login_info": json.RawMessage(`{"uin":10001}`),
+	})
+
+	out := map[string]any{}
+	if err := h.Post(context.Background(), "get_login_info", map[string]int{"a": 1}, &out); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+	if number, _ := out["uin"].(json.Number); number.String() != "10001" {
+		t.Errorf("uin = %v, want 10001", out["uin"])
+	}
+
+	sent := emi_core.SendGroupMessageRequest{GroupID: 100}
+	if _, err := h.SendGroupMessage(context.Background(), sent); err != nil {
+		t.Errorf("SendGroupMessage without a fixture returned %v", err)
+	}
+
+	requests := recorder.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("recorded %d requests, want 2", len(requests))
+	}
+	if requests[0].Endpoint != "get_login_info" || string(requests[0].Request) != `{"a":1}` {
+		t.Errorf("first recorded request = %s %s", requests[0].Endpoint, requests[0].Request)
+	}
+	if requests[1].Endpoint != string(emi_core.SendGroupMessage) {
+		t.Errorf("second recorded endpoint = %s", requests[1].Endpoint)
+	}
+	recorded := emi_core.SendGroupMessageRequest{}
+	if err := json.Unmarshal(requests[1].Request, &recorded); err != nil || !reflect.DeepEqual(recorded, sent) {
+		t.Errorf("second recorded request = %s, want %+v", requests[1].Request, sent)
+	}
+}
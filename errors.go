@@ -0,0 +1,71 @@
+package emi_transport
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrClosed = errors.New("client closed")
+
+var ErrNoData = errors.New("response contains no data")
+
+// 重试次数耗尽
+type RetryExhaustedError struct {
+	Attempts int           // 总尝试次数
+	Elapsed  time.Duration // 从第一次尝试开始经过的时间
+	Last     error         // 最后一次尝试的错误
+}
+
+func (e *RetryExhaustedError) Error() string {
+	return fmt.Sprintf("max retries exceeded after %d attempts in %s: %v", e.Attempts, e.Elapsed, e.Last)
+}
+
+func (e *RetryExhaustedError) Unwrap() error {
+	return e.Last
+}
+
+// 协议端返回了非 2xx 的 HTTP 状态码
+type StatusError struct {
+	StatusCode int    // HTTP 状态码
+	Body       []byte // 响应体
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Body) == 0 {
+		return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code %d, response body: %s", e.StatusCode, string(e.Body))
+}
+
+// WebSocket 握手失败
+type WSHandshakeError struct {
+	Gateway    string // 拨号的地址，其中的 access token 已被遮盖
+	StatusCode int    // 握手响应的 HTTP 状态码，未收到响应时为 0
+	Err        error  // Dialer 返回的错误
+}
+
+func (e *WSHandshakeError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("websocket handshake with %s failed: %v", e.Gateway, e.Err)
+	}
+	return fmt.Sprintf("websocket handshake with %s failed with status code %d: %v", e.Gateway, e.StatusCode, e.Err)
+}
+
+func (e *WSHandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// 协议端返回的业务错误
+type APIError struct {
+	Status  string // 响应中的 status 字段
+	Code    int    // 响应中的 retcode 字段
+	Message string // 响应中的 message 字段
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("api error: status %q, retcode %d", e.Status, e.Code)
+	}
+	return fmt.Sprintf("api error: status %q, retcode %d: %s", e.Status, e.Code, e.Message)
+}
@@ -4,20 +4,46 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	emi_core "github.com/aK1r4z/emi-core"
 )
 
 type HttpResult struct {
-	Status string          `json:"status"`
-	Code   int             `json:"retcode"`
-	Data   json.RawMessage `json:"data"`
+	Status  string          `json:"status"`
+	Code    int             `json:"retcode"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// 自定义重试判断，返回是否重试以及重试前的等待时间，attempt 从 0 开始
+type RetryPredicate func(endpoint string, attempt int, err error) (bool, time.Duration)
+
+// HttpClient 的请求统计快照
+type HttpStats struct {
+	Requests int64 // 请求总数
+	Retries  int64 // 重试总数
+	Failures int64 // 失败总数
+	InFlight int64 // 正在进行的请求数
+
+	LastSuccessAt time.Time // 最后一次请求成功的时间，尚未成功时为零值
+}
+
+type httpCounters struct {
+	requests atomic.Int64
+	retries  atomic.Int64
+	failures atomic.Int64
+	inFlight atomic.Int64
+
+	lastSuccessAt atomic.Int64
 }
 
 type HttpClient struct {
@@ -25,19 +51,67 @@ type HttpClient struct {
 
 	restGateway string
 	accessToken string
+	auth        authConfig
+
+	apiPrefix string
 
 	client http.Client
 
+	compressThreshold int
+	compressRejected  atomic.Bool
+
+	logBodyLimit int
+
+	requestInterceptors  []RequestInterceptor
+	responseInterceptors []ResponseInterceptor
+
 	maxRetries int
 
+	errorOnNoData bool
+	expectsData   map[string]bool
+
+	okStatuses map[string]struct{}
+
+	defaultCallTimeout time.Duration
+
 	baseRetryDelay time.Duration
 	maxRetryDelay  time.Duration
 	maxRetryJitter time.Duration
+
+	maxTotalRetryDuration time.Duration
+
+	jitterStrategy JitterStrategy
+
+	shouldRetry RetryPredicate
+
+	clock Clock
+
+	breaker *CircuitBreaker
+
+	tracer Tracer
+
+	batchConcurrency int
+
+	counters httpCounters
+
+	dryRun *dryRun
+
+	reactions reactionTracker
+
+	closed atomic.Bool
+
+	randMutex sync.Mutex
+	rand      *rand.Rand
 }
 
+var _ APIClient = (*HttpClient)(nil)
+
+// 调试日志中请求体与响应体的默认最大长度
+const defaultLogBodyLimit = 4096
+
 func NewHttpClient(logger Logger, restGateway string, accessToken string) *HttpClient {
-	return &HttpClient{
-		logger: logger,
+	h := &HttpClient{
+		logger: logger.Named("http"),
 
 		restGateway: restGateway,
 		accessToken: accessToken,
@@ -51,7 +125,14 @@ func NewHttpClient(logger Logger, restGateway string, accessToken string) *HttpC
 		baseRetryDelay: 100 * time.Millisecond,
 		maxRetryDelay:  5 * time.Second,
 		maxRetryJitter: 100 * time.Millisecond,
+
+		logBodyLimit: defaultLogBodyLimit,
+
+		clock:  realClock{},
+		tracer: noopTracer{},
 	}
+	h.configureUnixSocket()
+	return h
 }
 
 func NewHttpClientWithOptions(
@@ -68,8 +149,8 @@ func NewHttpClientWithOptions(
 	maxRetryDelay time.Duration,
 	maxRetryJitter time.Duration,
 ) *HttpClient {
-	return &HttpClient{
-		logger: logger,
+	h := &HttpClient{
+		logger: logger.Named("http"),
 
 		restGateway: restGateway,
 		accessToken: accessToken,
@@ -81,56 +162,356 @@ func NewHttpClientWithOptions(
 		baseRetryDelay: baseRetryDelay,
 		maxRetryDelay:  maxRetryDelay,
 		maxRetryJitter: maxRetryJitter,
+
+		logBodyLimit: defaultLogBodyLimit,
+
+		clock:  realClock{},
+		tracer: noopTracer{},
 	}
+	h.configureUnixSocket()
+	return h
+}
+
+// 如果 restGateway 为 unix:// 地址，改为通过 unix 套接字发送请求
+func (h *HttpClient) configureUnixSocket() {
+	socketPath, _, ok := parseUnixGateway(h.restGateway)
+	if !ok {
+		return
+	}
+
+	transport, ok := unixTransport(socketPath, h.client.Transport)
+	if !ok {
+		h.logger.Errorf("Cannot dial unix socket %s through http.Client.Transport of type %T, the transport must dial the socket itself", socketPath, transport)
+	}
+
+	h.restGateway = "http://" + unixSocketHost
+	h.client.Transport = transport
+}
+
+// 设置 access token 的携带方式，scheme 仅在 AuthScheme 模式下使用
+func (h *HttpClient) SetAuthMode(mode AuthMode, scheme string) {
+	h.auth = authConfig{
+		mode:   mode,
+		scheme: scheme,
+	}
+}
+
+// 设置 API 路径前缀，拼接在 restGateway 与接口名之间，例如 "/milky/api"
+func (h *HttpClient) SetAPIPrefix(prefix string) {
+	h.apiPrefix = prefix
+}
+
+// 设置调试日志中请求体与响应体的最大长度，超出时只记录前 limit 字节与总长度
+//
+// limit 为 0 时只记录长度，为负数时不截断。
+func (h *HttpClient) SetLogBodyLimit(limit int) {
+	h.logBodyLimit = limit
+}
+
+// 设置重试退避的抖动策略
+func (h *HttpClient) SetJitterStrategy(strategy JitterStrategy) {
+	h.jitterStrategy = strategy
+}
+
+// 设置自定义重试判断，设置后完全替代内置的重试次数与退避逻辑，为 nil 时使用内置逻辑
+func (h *HttpClient) SetRetryPredicate(predicate RetryPredicate) {
+	h.shouldRetry = predicate
+}
+
+// 设置重试等待所使用的时钟，为 nil 时使用系统时间
+func (h *HttpClient) SetClock(clock Clock) {
+	if clock == nil {
+		clock = realClock{}
+	}
+	h.clock = clock
+}
+
+// 设置计算退避抖动所使用的随机源，为 nil 时使用全局随机源
+func (h *HttpClient) SetRandSource(source rand.Source) {
+	h.randMutex.Lock()
+	defer h.randMutex.Unlock()
+
+	if source == nil {
+		h.rand = nil
+		return
+	}
+	h.rand = rand.New(source)
+}
+
+// 设置熔断器，为 nil 时不启用熔断
+func (h *HttpClient) SetCircuitBreaker(breaker *CircuitBreaker) {
+	h.breaker = breaker
+}
+
+// 获取请求统计快照
+func (h *HttpClient) Stats() HttpStats {
+	stats := HttpStats{
+		Requests: h.counters.requests.Load(),
+		Retries:  h.counters.retries.Load(),
+		Failures: h.counters.failures.Load(),
+		InFlight: h.counters.inFlight.Load(),
+	}
+	if lastSuccessAt := h.counters.lastSuccessAt.Load(); lastSuccessAt != 0 {
+		stats.LastSuccessAt = time.Unix(0, lastSuccessAt)
+	}
+	return stats
+}
+
+// 设置响应没有数据（204、data 为空或 null）时是否返回 ErrNoData，默认视为空响应
+func (h *HttpClient) SetErrorOnNoData(enabled bool) {
+	h.errorOnNoData = enabled
+}
+
+// 为单个接口设置响应没有数据时是否返回 ErrNoData，优先于 SetErrorOnNoData 的全局设置
+//
+// 例如 get_login_info 总是应当返回数据，可以设置为 true 以发现协议端的异常空响应。
+func (h *HttpClient) SetEndpointExpectsData(endpoint string, expects bool) {
+	if h.expectsData == nil {
+		h.expectsData = map[string]bool{}
+	}
+	h.expectsData[endpoint] = expects
+}
+
+// 设置视为成功的 status 取值，其余取值返回 *APIError
+//
+// 默认为 "ok" 与空字符串（部分实现不返回 status）。
+func (h *HttpClient) SetOKStatuses(statuses ...string) {
+	okStatuses := make(map[string]struct{}, len(statuses))
+	for _, status := range statuses {
+		okStatuses[status] = struct{}{}
+	}
+	h.okStatuses = okStatuses
+}
+
+// 判断 status 是否表示成功
+func (h *HttpClient) isOKStatus(status string) bool {
+	if h.okStatuses == nil {
+		return status == "ok" || status == ""
+	}
+	_, ok := h.okStatuses[status]
+	return ok
+}
+
+// 设置默认调用超时
+//
+// 调用方传入的 context 没有截止时间时（例如 context.Background()），
+// 整个调用（包括所有重试）以该超时为限。不大于 0 时不启用（默认）。
+func (h *HttpClient) SetDefaultCallTimeout(timeout time.Duration) {
+	h.defaultCallTimeout = timeout
+}
+
+// 设置一次调用中所有重试的最长总耗时
+//
+// 等待下一次重试会超出该时长时停止重试并返回 *RetryExhaustedError，与重试次数无关。
+// 不大于 0 时不限制（默认）。
+func (h *HttpClient) SetMaxTotalRetryDuration(duration time.Duration) {
+	h.maxTotalRetryDuration = duration
+}
+
+// 关闭客户端，关闭后的请求都会返回 ErrClosed，重复关闭不会报错
+func (h *HttpClient) Close() error {
+	if h.closed.Swap(true) {
+		return nil
+	}
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+// 客户端是否已关闭
+func (h *HttpClient) Closed() bool {
+	return h.closed.Load()
+}
+
+type noRetryKey struct{}
+
+// 返回不重试的 context，使用该 context 的请求只会尝试一次
+func WithNoRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+func isNoRetry(ctx context.Context) bool {
+	noRetry, _ := ctx.Value(noRetryKey{}).(bool)
+	return noRetry
+}
+
+// 请求的元信息
+type ResponseMeta struct {
+	Attempts int // 实际尝试次数，1 表示没有重试
 }
 
 func (h *HttpClient) Post(ctx context.Context, endpoint string, request any, response any) error {
+	_, err := h.PostWithMeta(ctx, endpoint, request, response)
+	return err
+}
+
+// 与 Post 相同，但只尝试一次，失败时直接返回错误
+func (h *HttpClient) PostOnce(ctx context.Context, endpoint string, request any, response any) error {
+	return h.Post(WithNoRetry(ctx), endpoint, request, response)
+}
+
+// 与 Post 相同，同时返回请求的元信息
+func (h *HttpClient) PostWithMeta(ctx context.Context, endpoint string, request any, response any) (meta ResponseMeta, err error) {
+	if h.closed.Load() {
+		return meta, ErrClosed
+	}
+
+	h.counters.requests.Add(1)
+	h.counters.inFlight.Add(1)
+	defer func() {
+		h.counters.inFlight.Add(-1)
+		if err != nil {
+			h.counters.failures.Add(1)
+		} else {
+			h.counters.lastSuccessAt.Store(h.clock.Now().UnixNano())
+		}
+	}()
+
+	callCtx := ctx
+	if _, ok := ctx.Deadline(); !ok && h.defaultCallTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, h.defaultCallTimeout)
+		defer cancel()
+	}
+
+	callCtx, span := h.tracer.Start(callCtx, "emi.http "+endpoint)
+	span.SetAttribute("emi.endpoint", endpoint)
+	defer func() {
+		span.SetAttribute("emi.attempts", meta.Attempts)
+		if err != nil {
+			span.SetAttribute("emi.status", "error")
+			span.RecordError(err)
+		} else {
+			span.SetAttribute("emi.status", "ok")
+		}
+		span.End()
+	}()
+
+	if h.breaker == nil {
+		err = h.post(callCtx, endpoint, request, response, &meta)
+		return meta, err
+	}
+
+	if err := h.breaker.allow(h.clock.Now()); err != nil {
+		return meta, err
+	}
+
+	// 调用方主动取消的请求不计入熔断统计；
+	// 业务错误与缺少数据说明协议端工作正常，按成功计入
+	err = h.post(callCtx, endpoint, request, response, &meta)
+	if err != nil && ctx.Err() != nil {
+		h.breaker.release()
+	} else {
+		h.breaker.record(h.clock.Now(), err == nil || !isRetryable(err))
+	}
+
+	return meta, err
+}
+
+func (h *HttpClient) post(ctx context.Context, endpoint string, request any, response any, meta *ResponseMeta) error {
+	if h.dryRun != nil {
+		h.logger.Debugf("Recording post request to %s (dry-run)", endpoint)
+		meta.Attempts = 1
+		return h.dryRun.post(endpoint, request, response)
+	}
+
 	h.logger.Debugf("Sending post request to %s", endpoint)
-	urlPath, err := url.JoinPath(h.restGateway, endpoint)
+	urlPath, err := url.JoinPath(h.restGateway, h.apiPrefix, endpoint)
 	if err != nil {
 		return fmt.Errorf("failed to join URL path: %w", err)
 	}
 	h.logger.Debugf("URL path: %s", urlPath)
 
-	attempt := 0
+	policy := h.retryPolicy(endpoint)
+	if isNoRetry(ctx) {
+		policy.ShouldRetry = func(int, error) (bool, time.Duration) {
+			return false, 0
+		}
+	}
 
-	for {
-		err := h.doPost(ctx, urlPath, request, response)
-		if err == nil {
-			return nil
-		} else if attempt > h.maxRetries {
-			return fmt.Errorf("max retries exceeded: %w", err)
+	return Retry(ctx, policy, func() error {
+		meta.Attempts += 1
+		if meta.Attempts > 1 {
+			h.counters.retries.Add(1)
+		}
+
+		err := h.doPost(ctx, endpoint, urlPath, request, response)
+		if err != nil && h.shouldRetry == nil && !isRetryable(err) {
+			return Permanent(err)
 		}
+		return err
+	})
+}
+
+// 构建调用 endpoint 时使用的重试策略
+func (h *HttpClient) retryPolicy(endpoint string) RetryPolicy {
+	policy := RetryPolicy{
+		MaxRetries: h.maxRetries,
 
-		// 请求失败，开始重试
-		jitter := time.Duration(rand.Int64N(int64(h.maxRetryJitter)))
-		delay := min(
-			h.baseRetryDelay*(1<<attempt)+jitter,
-			h.maxRetryDelay,
-		)
+		BaseDelay: h.baseRetryDelay,
+		MaxDelay:  h.maxRetryDelay,
+		MaxJitter: h.maxRetryJitter,
 
-		h.logger.Debugf("Retrying request to %s after %s (attempt %d/%d)", endpoint, delay, attempt, h.maxRetries)
+		Jitter: h.jitterStrategy,
+
+		MaxElapsed: h.maxTotalRetryDuration,
+
+		OnRetry: func(attempt int, delay time.Duration, err error) {
+			h.logger.Debugf("Retrying request to %s after %s (retry %d/%d)", endpoint, delay, attempt+1, h.maxRetries)
+		},
+
+		Clock:      h.clock,
+		RandInt64N: h.randInt64N,
+	}
 
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("context canceled")
-		case <-time.After(delay):
+	if h.shouldRetry != nil {
+		policy.ShouldRetry = func(attempt int, err error) (bool, time.Duration) {
+			return h.shouldRetry(endpoint, attempt, err)
 		}
+	}
+
+	return policy
+}
+
+// 判断错误是否值得重试，业务错误与缺少数据不会因重试而改变
+func isRetryable(err error) bool {
+	apiError := &APIError{}
+	return !errors.Is(err, ErrNoData) && !errors.As(err, &apiError)
+}
+
+// 返回 [0, n) 内的随机数
+func (h *HttpClient) randInt64N(n int64) int64 {
+	h.randMutex.Lock()
+	defer h.randMutex.Unlock()
 
-		attempt += 1
+	if h.rand == nil {
+		return rand.Int64N(n)
 	}
+	return h.rand.Int64N(n)
 }
 
-func (h *HttpClient) doPost(ctx context.Context, urlPath string, request any, response any) error {
+func (h *HttpClient) doPost(ctx context.Context, endpoint string, urlPath string, request any, response any) error {
 
 	// 构建 HTTP 请求体
 	var bodyReader io.Reader = bytes.NewReader([]byte{})
+	compressed := false
 	if request != nil {
 		jsonBytes, err := json.Marshal(request)
 		if err != nil {
 			return fmt.Errorf("failed to marshal request: %w", err)
 		}
-		h.logger.Debugf("Request body: %s", string(jsonBytes))
+		h.logger.Debugf("Request body: %s", h.logBody(jsonBytes))
+
+		// 压缩较大的请求体
+		if h.shouldCompress(jsonBytes) {
+			gzipped, err := gzipBytes(jsonBytes)
+			if err != nil {
+				return fmt.Errorf("failed to compress request: %w", err)
+			}
+			h.logger.Debugf("Compressed request body from %d to %d bytes", len(jsonBytes), len(gzipped))
+			jsonBytes = gzipped
+			compressed = true
+		}
+
 		bodyReader = bytes.NewReader(jsonBytes)
 	}
 
@@ -142,8 +523,15 @@ func (h *HttpClient) doPost(ctx context.Context, urlPath string, request any, re
 
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
-	if h.accessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+h.accessToken)
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	h.auth.apply(req.Header, req.URL, h.accessToken)
+
+	for _, interceptor := range h.requestInterceptors {
+		if err := interceptor(req); err != nil {
+			return fmt.Errorf("request interceptor failed: %w", err)
+		}
 	}
 
 	// 发送 HTTP 请求
@@ -158,13 +546,37 @@ func (h *HttpClient) doPost(ctx context.Context, urlPath string, request any, re
 	if err != nil {
 		return fmt.Errorf("failed to read response body: %w", err)
 	}
-	h.logger.Debugf("response body: %s", string(body))
+	h.logger.Debugf("response body: %s", h.logBody(body))
+
+	for _, interceptor := range h.responseInterceptors {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err := interceptor(resp); err != nil {
+			return fmt.Errorf("response interceptor failed: %w", err)
+		}
+	}
+
+	// 协议端不支持压缩的请求体，停用压缩后由重试重新发送
+	if compressed && resp.StatusCode == http.StatusUnsupportedMediaType {
+		h.compressRejected.Store(true)
+		h.logger.Warnf("Gateway rejected gzip request body, disabling request compression")
+	}
 
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return fmt.Errorf("unexpected status code %d, response body: %s", resp.StatusCode, string(body))
+		return &StatusError{
+			StatusCode: resp.StatusCode,
+			Body:       body,
+		}
+	}
+
+	if resp.StatusCode == http.StatusNoContent {
+		if response == nil {
+			return nil
+		}
+		return h.noData(endpoint)
 	}
 
-	if response == nil || resp.StatusCode == http.StatusNoContent {
+	// 调用方不需要返回数据时，空响应体视为成功
+	if response == nil && len(bytes.TrimSpace(body)) == 0 {
 		return nil
 	}
 
@@ -174,16 +586,66 @@ func (h *HttpClient) doPost(ctx context.Context, urlPath string, request any, re
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if err := json.Unmarshal(result.Data, response); err != nil {
-		if err == io.EOF {
-			return nil
+	if !h.isOKStatus(result.Status) {
+		return &APIError{
+			Status:  result.Status,
+			Code:    result.Code,
+			Message: result.Message,
 		}
+	}
+
+	// 调用方不需要返回数据时只检查 status
+	if response == nil {
+		return nil
+	}
+
+	if isEmptyData(result.Data) {
+		return h.noData(endpoint)
+	}
+
+	if err := decodeJSON(result.Data, response); err != nil {
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	return nil
 }
 
+// 按照 logBodyLimit 截断要写入日志的请求体或响应体
+func (h *HttpClient) logBody(body []byte) string {
+	if h.logBodyLimit < 0 || len(body) <= h.logBodyLimit {
+		return string(body)
+	}
+	if h.logBodyLimit == 0 {
+		return fmt.Sprintf("<%d bytes>", len(body))
+	}
+	return fmt.Sprintf("%s... <%d bytes>", body[:h.logBodyLimit], len(body))
+}
+
+// 解码响应数据，解码到 any 或 map[string]any 时数字保留为 json.Number，避免大于 2^53 的 QQ 号丢失精度
+func decodeJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// 响应没有数据时的返回值
+func (h *HttpClient) noData(endpoint string) error {
+	expects, ok := h.expectsData[endpoint]
+	if !ok {
+		expects = h.errorOnNoData
+	}
+	if expects {
+		return ErrNoData
+	}
+	return nil
+}
+
+// data 字段是否为空或 null
+func isEmptyData(data json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
 // SystemAPI
 
 // 获取登录信息
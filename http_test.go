@@ -0,0 +1,393 @@
+package emi_transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 创建指向 handler 的 HttpClient，重试等待使用 fakeClock 因而不会真正休眠
+func newTestHttpClient(t *testing.T, handler http.HandlerFunc) (*HttpClient, *syncBuffer) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	logger, buffer := newTestLogger()
+	h := NewHttpClientWithOptions(logger, server.URL, "secret-token", http.Client{}, 2, 100*time.Millisecond, time.Second, 0)
+	h.SetClock(newFakeClock())
+	return h, buffer
+}
+
+// 写入 Milky 格式的响应
+func writeResult(w http.ResponseWriter, status string, retcode int, data any) {
+	raw, _ := json.Marshal(data)
+	json.NewEncoder(w).Encode(HttpResult{
+		Status: status,
+		Code:   retcode,
+		Data:   raw,
+	})
+}
+
+func TestPostDecodesData(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/get_login_info" {
+			t.Errorf("path = %s, want /get_login_info", r.URL.Path)
+		}
+		writeResult(w, "ok", 0, map[string]any{"nickname": "emi"})
+	})
+
+	out := map[string]any{}
+	if err := h.Post(context.Background(), "get_login_info", struct{}{}, &out); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+	if out["nickname"] != "emi" {
+		t.Errorf("nickname = %v, want emi", out["nickname"])
+	}
+}
+
+func TestPostRetriesAndCountsStats(t *testing.T) {
+	hits := atomic.Int32{}
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+
+	if err := h.Post(context.Background(), "x", nil, &map[string]any{}); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+
+	stats := h.Stats()
+	if stats.Requests != 1 || stats.Retries != 2 || stats.Failures != 0 || stats.InFlight != 0 {
+		t.Errorf("Stats = %+v, want 1 request, 2 retries, 0 failures, 0 in flight", stats)
+	}
+	if stats.LastSuccessAt.IsZero() {
+		t.Errorf("LastSuccessAt is zero after a successful call")
+	}
+}
+
+func TestPostWithMetaReportsAttempts(t *testing.T) {
+	hits := atomic.Int32{}
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+
+	meta, err := h.PostWithMeta(context.Background(), "x", nil, &map[string]any{})
+	if err != nil {
+		t.Fatalf("PostWithMeta returned %v", err)
+	}
+	if meta.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3", meta.Attempts)
+	}
+
+	meta, err = h.PostWithMeta(context.Background(), "x", nil, &map[string]any{})
+	if err != nil || meta.Attempts != 1 {
+		t.Errorf("PostWithMeta = %+v, %v, want 1 attempt without retries", meta, err)
+	}
+}
+
+func TestPostOnceDoesNotRetry(t *testing.T) {
+	hits := atomic.Int32{}
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("bad gateway"))
+	})
+
+	err := h.PostOnce(context.Background(), "x", nil, &map[string]any{})
+
+	statusError := &StatusError{}
+	if !errors.As(err, &statusError) {
+		t.Fatalf("PostOnce returned %T %v, want *StatusError", err, err)
+	}
+	if statusError.StatusCode != http.StatusBadGateway || string(statusError.Body) != "bad gateway" {
+		t.Errorf("StatusError = %+v", statusError)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("hits = %d, want 1", hits.Load())
+	}
+}
+
+func TestPostRetryExhaustedWrapsStatusError(t *testing.T) {
+	hits := atomic.Int32{}
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	err := h.Post(context.Background(), "x", nil, &map[string]any{})
+
+	exhausted := &RetryExhaustedError{}
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("Post returned %T %v, want *RetryExhaustedError", err, err)
+	}
+	statusError := &StatusError{}
+	if !errors.As(err, &statusError) || statusError.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("errors.As(err, *StatusError) failed for %v", err)
+	}
+	if hits.Load() != 3 {
+		t.Errorf("hits = %d, want 3 for maxRetries 2", hits.Load())
+	}
+}
+
+func TestPostAPIError(t *testing.T) {
+	for _, name := range []string{"with response", "nil response"} {
+		hits := atomic.Int32{}
+		h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			json.NewEncoder(w).Encode(HttpResult{Status: "failed", Code: 1400, Message: "denied"})
+		})
+
+		var response any
+		if name == "with response" {
+			response = &map[string]any{}
+		}
+		err := h.Post(context.Background(), "x", nil, response)
+
+		apiError := &APIError{}
+		if !errors.As(err, &apiError) {
+			t.Fatalf("%s: Post returned %T %v, want *APIError", name, err, err)
+		}
+		if apiError.Code != 1400 || apiError.Message != "denied" {
+			t.Errorf("%s: APIError = %+v", name, apiError)
+		}
+		if hits.Load() != 1 {
+			t.Errorf("%s: hits = %d, want 1 (APIError must not be retried)", name, hits.Load())
+		}
+	}
+}
+
+func TestPostOKStatuses(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, "success", 0, map[string]any{})
+	})
+
+	if err := h.Post(context.Background(), "x", nil, &map[string]any{}); err == nil {
+		t.Errorf("Post accepted status success with default ok statuses")
+	}
+
+	h.SetOKStatuses("success")
+	if err := h.Post(context.Background(), "x", nil, &map[string]any{}); err != nil {
+		t.Errorf("Post returned %v after SetOKStatuses", err)
+	}
+}
+
+func TestPostEmptyStatus(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, "", 0, map[string]any{"value": 1})
+	})
+	ctx := context.Background()
+
+	// 部分实现不返回 status，默认视为成功
+	out := map[string]any{}
+	if err := h.Post(ctx, "x", nil, &out); err != nil || out["value"] == nil {
+		t.Errorf("Post = %v, %v, want an empty status to be accepted by default", out, err)
+	}
+	if err := h.Post(ctx, "x", nil, nil); err != nil {
+		t.Errorf("Post with a nil response returned %v for an empty status", err)
+	}
+
+	h.SetOKStatuses("ok")
+	apiError := &APIError{}
+	if err := h.Post(ctx, "x", nil, &map[string]any{}); !errors.As(err, &apiError) || apiError.Status != "" {
+		t.Errorf("Post returned %v, want *APIError for an empty status once only ok is accepted", err)
+	}
+}
+
+func TestPostNoData(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/no_content" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeResult(w, "ok", 0, nil)
+	})
+	ctx := context.Background()
+
+	for _, endpoint := range []string{"no_content", "null_data"} {
+		if err := h.Post(ctx, endpoint, nil, &map[string]any{}); err != nil {
+			t.Errorf("%s: Post returned %v by default", endpoint, err)
+		}
+	}
+
+	h.SetErrorOnNoData(true)
+	for _, endpoint := range []string{"no_content", "null_data"} {
+		if err := h.Post(ctx, endpoint, nil, &map[string]any{}); !errors.Is(err, ErrNoData) {
+			t.Errorf("%s: Post returned %v, want ErrNoData", endpoint, err)
+		}
+	}
+}
+
+func TestPostEndpointExpectsData(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/no_content" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeResult(w, "ok", 0, nil)
+	})
+	ctx := context.Background()
+
+	h.SetEndpointExpectsData("null_data", true)
+	if err := h.Post(ctx, "null_data", nil, &map[string]any{}); !errors.Is(err, ErrNoData) {
+		t.Errorf("null_data: Post returned %v, want ErrNoData for an endpoint expecting data", err)
+	}
+	if err := h.Post(ctx, "no_content", nil, &map[string]any{}); err != nil {
+		t.Errorf("no_content: Post returned %v, other endpoints must keep the default", err)
+	}
+
+	h.SetErrorOnNoData(true)
+	h.SetEndpointExpectsData("no_content", false)
+	if err := h.Post(ctx, "no_content", nil, &map[string]any{}); err != nil {
+		t.Errorf("no_content: Post returned %v, the endpoint setting must override the global one", err)
+	}
+}
+
+func TestPostKeepsLargeNumbers(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok","retcode":0,"data":{"user_id":9007199254740993}}`))
+	})
+
+	out := map[string]any{}
+	if err := h.Post(context.Background(), "x", nil, &out); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+	number, ok := out["user_id"].(json.Number)
+	if !ok || number.String() != "9007199254740993" {
+		t.Errorf("user_id = %#v, want json.Number 9007199254740993", out["user_id"])
+	}
+}
+
+func TestPostAfterClose(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent after Close")
+	})
+
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("second Close returned %v", err)
+	}
+	if err := h.Post(context.Background(), "x", nil, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Post returned %v, want ErrClosed", err)
+	}
+}
+
+func TestPostDefaultCallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+
+	h.SetDefaultCallTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	err := h.PostOnce(context.Background(), "x", nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PostOnce returned %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("PostOnce took %s", elapsed)
+	}
+}
+
+func TestPostDefaultCallTimeoutKeepsCallerDeadline(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+	h.SetDefaultCallTimeout(50 * time.Millisecond)
+
+	// 调用方已经设置了截止时间时不使用默认超时
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := h.PostOnce(ctx, "x", nil, nil); err != nil {
+		t.Errorf("PostOnce returned %v, the default timeout must not shorten the caller's deadline", err)
+	}
+}
+
+func TestPostLogBodyLimit(t *testing.T) {
+	h, logs := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+	h.SetLogBodyLimit(16)
+
+	request := map[string]string{"data": strings.Repeat("x", 1000)}
+	if err := h.Post(context.Background(), "x", request, nil); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+
+	output := logs.String()
+	if strings.Contains(output, strings.Repeat("x", 100)) {
+		t.Errorf("log contains the full request body")
+	}
+	if !strings.Contains(output, "<1011 bytes>") {
+		t.Errorf("log does not contain the body size, got:\n%s", output)
+	}
+}
+
+func TestPostAPIPrefix(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/milky/api/get_login_info" {
+			t.Errorf("path = %s, want /milky/api/get_login_info", r.URL.Path)
+		}
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+	h.SetAPIPrefix("/milky/api")
+
+	if err := h.Post(context.Background(), "get_login_info", nil, nil); err != nil {
+		t.Errorf("Post returned %v", err)
+	}
+}
+
+func TestPostRetryPredicate(t *testing.T) {
+	hits := atomic.Int32{}
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		json.NewEncoder(w).Encode(HttpResult{Status: "failed", Code: 1500, Message: "busy"})
+	})
+	clock := newFakeClock()
+	h.SetClock(clock)
+
+	// 自定义判断替代内置逻辑，业务错误也可以被重试
+	endpoints := []string{}
+	h.SetRetryPredicate(func(endpoint string, attempt int, err error) (bool, time.Duration) {
+		endpoints = append(endpoints, endpoint)
+		return attempt < 1, 10 * time.Millisecond
+	})
+
+	err := h.Post(context.Background(), "send_group_message", nil, &map[string]any{})
+
+	apiError := &APIError{}
+	if !errors.As(err, &apiError) {
+		t.Errorf("Post returned %T %v, want *APIError", err, err)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("hits = %d, want 2", hits.Load())
+	}
+	if len(endpoints) == 0 || endpoints[0] != "send_group_message" {
+		t.Errorf("predicate endpoints = %v, want send_group_message", endpoints)
+	}
+	if sleeps := clock.Sleeps(); len(sleeps) != 1 || sleeps[0] != 10*time.Millisecond {
+		t.Errorf("retry delays = %v, want [10ms]", sleeps)
+	}
+}
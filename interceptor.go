@@ -0,0 +1,21 @@
+package emi_transport
+
+import "net/http"
+
+// 请求拦截器，在每次发送请求之前调用，返回错误时放弃本次请求
+type RequestInterceptor func(*http.Request) error
+
+// 响应拦截器，在每次收到响应之后调用，返回错误时本次请求视为失败
+//
+// 调用时响应体已被读取，resp.Body 可以重新读取完整内容。
+type ResponseInterceptor func(*http.Response) error
+
+// 添加请求拦截器，按添加顺序执行
+func (h *HttpClient) AddRequestInterceptor(interceptor RequestInterceptor) {
+	h.requestInterceptors = append(h.requestInterceptors, interceptor)
+}
+
+// 添加响应拦截器，按添加顺序执行
+func (h *HttpClient) AddResponseInterceptor(interceptor ResponseInterceptor) {
+	h.responseInterceptors = append(h.responseInterceptors, interceptor)
+}
@@ -0,0 +1,90 @@
+package emi_transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Trace"); got != "abc" {
+			t.Errorf("X-Trace = %q, want abc", got)
+		}
+		writeResult(w, "ok", 0, map[string]any{"value": 1})
+	})
+
+	h.AddRequestInterceptor(func(req *http.Request) error {
+		req.Header.Set("X-Trace", "abc")
+		return nil
+	})
+
+	seen := []string{}
+	for i := 0; i < 2; i++ {
+		h.AddResponseInterceptor(func(resp *http.Response) error {
+			body, err := io.ReadAll(resp.Body)
+			seen = append(seen, string(body))
+			return err
+		})
+	}
+
+	out := map[string]any{}
+	if err := h.Post(context.Background(), "x", nil, &out); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+	if len(seen) != 2 || !strings.Contains(seen[0], `"value":1`) || seen[0] != seen[1] {
+		t.Errorf("response interceptors saw %q, want the same body twice", seen)
+	}
+	if out["value"] == nil {
+		t.Errorf("response not decoded after interceptors read the body")
+	}
+}
+
+func TestRequestInterceptorError(t *testing.T) {
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent after interceptor error")
+	})
+
+	h.AddRequestInterceptor(func(req *http.Request) error {
+		return errTest
+	})
+
+	if err := h.PostOnce(context.Background(), "x", nil, nil); !errors.Is(err, errTest) {
+		t.Errorf("PostOnce returned %v, want the interceptor error", err)
+	}
+}
+
+func TestInterceptorsRunOnEveryAttempt(t *testing.T) {
+	hits := atomic.Int32{}
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+
+	requests, responses := atomic.Int32{}, atomic.Int32{}
+	h.AddRequestInterceptor(func(req *http.Request) error {
+		requests.Add(1)
+		return nil
+	})
+	h.AddResponseInterceptor(func(resp *http.Response) error {
+		responses.Add(1)
+		return nil
+	})
+
+	if err := h.Post(context.Background(), "x", nil, &map[string]any{}); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("hits = %d, want 2", hits.Load())
+	}
+	if requests.Load() != 2 || responses.Load() != 2 {
+		t.Errorf("request interceptor ran %d times, response interceptor %d times, want 2 each", requests.Load(), responses.Load())
+	}
+}
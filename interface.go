@@ -20,10 +20,16 @@ type Logger interface {
 	Warn(args ...any)
 	Error(args ...any)
 	Fatal(args ...any)
+
+	// 派生用于子系统的日志器，例如 "http"、"ws"
+	Named(name string) Logger
 }
 
+// 原始事件，与 emi_core.RawEvent 为同一类型
+type RawEvent = emi_core.RawEvent
+
 type EventSource interface {
-	Open(context.Context) (chan emi_core.RawEvent, error)
+	Open(context.Context) (chan RawEvent, error)
 	Close() error
 }
 
@@ -0,0 +1,12 @@
+package emi_transport
+
+import "context"
+
+// 调用任意接口并把响应的 data 字段解码为 T，适用于尚未封装的实验性接口
+func Invoke[T any](ctx context.Context, h *HttpClient, endpoint string, request any) (*T, error) {
+	var resp T
+	if err := h.Post(ctx, endpoint, request, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
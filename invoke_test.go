@@ -0,0 +1,28 @@
+package emi_transport
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestInvoke(t *testing.T) {
+	type echoResponse struct {
+		Message string `json:"message"`
+	}
+
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/custom_endpoint" {
+			t.Errorf("path = %s, want /custom_endpoint", r.URL.Path)
+		}
+		writeResult(w, "ok", 0, echoResponse{Message: "hello"})
+	})
+
+	resp, err := Invoke[echoResponse](context.Background(), h, "custom_endpoint", nil)
+	if err != nil {
+		t.Fatalf("Invoke returned %v", err)
+	}
+	if resp.Message != "hello" {
+		t.Errorf("Message = %q, want hello", resp.Message)
+	}
+}
@@ -1,8 +1,12 @@
 package emi_transport
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -38,29 +42,213 @@ func (l logLevel) String() string {
 	}
 }
 
+// 日志着色模式
+type ColorMode int
+
+const (
+	ColorAuto   ColorMode = 0 + iota // 输出到终端时着色（默认）
+	ColorAlways                      // 总是着色
+	ColorNever                       // 从不着色
+)
+
+// 日志等级对应的 ANSI 颜色
+func (l logLevel) color() string {
+	switch l {
+	case logLevelTrace:
+		return "\033[90m"
+	case logLevelDebug:
+		return "\033[36m"
+	case logLevelInfo:
+		return "\033[32m"
+	case logLevelWarn:
+		return "\033[33m"
+	case logLevelError:
+		return "\033[31m"
+	case logLevelFatal:
+		return "\033[1;31m"
+	default:
+		return ""
+	}
+}
+
+const colorReset = "\033[0m"
+
+// 日志输出格式
+type LogFormat int
+
+const (
+	LogFormatText LogFormat = 0 + iota // 便于阅读的文本格式（默认）
+	LogFormatJSON                      // 每行一个 JSON 对象
+)
+
+// JSON 格式的日志行
+type jsonLogLine struct {
+	Time  string `json:"time"`
+	Level string `json:"level"`
+	Name  string `json:"name"`
+	Msg   string `json:"msg"`
+}
+
 type TinyLogger struct {
 	name string
+
+	output *tinyLoggerOutput
 }
 
+// 输出设置，由日志器与通过 Named 派生的子日志器共享
+type tinyLoggerOutput struct {
+	sync.Mutex
+
+	writer    io.Writer
+	format    LogFormat
+	colorMode ColorMode
+	colored   bool
+
+	now func() time.Time
+}
+
+var _ Logger = (*TinyLogger)(nil)
+
 func NewTinyLogger(name string) *TinyLogger {
-	return &TinyLogger{
+	l := &TinyLogger{
 		name: name,
+
+		output: &tinyLoggerOutput{
+			writer:    os.Stdout,
+			colorMode: ColorAuto,
+
+			now: time.Now,
+		},
 	}
+	l.output.updateColored()
+	return l
+}
+
+// 派生子日志器，名称为 "<name>.<子系统名>"
+//
+// 子日志器与当前日志器共享输出设置，之后对任意一方调用 SetWriter 等方法都会同时生效。
+func (l *TinyLogger) Named(name string) Logger {
+	childName := name
+	if l.name != "" {
+		childName = l.name + "." + name
+	}
+
+	return &TinyLogger{
+		name: childName,
+
+		output: l.output,
+	}
+}
+
+// 设置日志输出位置，为 nil 时输出到标准输出
+func (l *TinyLogger) SetWriter(writer io.Writer) {
+	if writer == nil {
+		writer = os.Stdout
+	}
+
+	l.output.Lock()
+	defer l.output.Unlock()
+
+	l.output.writer = writer
+	l.output.updateColored()
+}
+
+// 设置日志输出格式
+func (l *TinyLogger) SetFormat(format LogFormat) {
+	l.output.Lock()
+	defer l.output.Unlock()
+
+	l.output.format = format
+}
+
+// 设置日志等级的着色模式
+func (l *TinyLogger) SetColorMode(mode ColorMode) {
+	l.output.Lock()
+	defer l.output.Unlock()
+
+	l.output.colorMode = mode
+	l.output.updateColored()
+}
+
+// 调用方需要持有锁
+func (o *tinyLoggerOutput) updateColored() {
+	switch o.colorMode {
+	case ColorAlways:
+		o.colored = true
+	case ColorNever:
+		o.colored = false
+	default:
+		o.colored = isTerminal(o.writer)
+	}
+}
+
+// 判断 writer 是否为终端
+func isTerminal(writer io.Writer) bool {
+	file, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// 设置获取当前时间的函数，为 nil 时使用 time.Now
+func (l *TinyLogger) SetNowFunc(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+
+	l.output.Lock()
+	defer l.output.Unlock()
+
+	l.output.now = now
 }
 
 func (l *TinyLogger) logF(logLevel logLevel, format string, args ...any) {
 	format = strings.TrimRight(format, "\n")
+	message := fmt.Sprintf(format, args...)
 
-	levelString := "[" + logLevel.String() + "]"
-	timeString := "[" + time.Now().Format("2006-01-02 15:04:05") + "]"
+	// 持有锁直到写入完成，多个协程的日志不会并发写入 writer，也不会与 SetWriter 等设置并发
+	l.output.Lock()
+	defer l.output.Unlock()
+
+	now := l.output.now()
+
+	if l.output.format == LogFormatJSON {
+		l.logJSON(logLevel, now, message)
+		return
+	}
+
+	levelString := fmt.Sprintf("%7s", "["+logLevel.String()+"]")
+	if l.output.colored {
+		levelString = logLevel.color() + levelString + colorReset
+	}
+	timeString := "[" + now.Format("2006-01-02 15:04:05") + "]"
 	nameString := "[" + l.name + "]"
 
-	logString := fmt.Sprintf(
-		"%s %7s %s: "+format+"\n",
-		append([]any{timeString, levelString, nameString}, args...)...,
-	)
+	logString := fmt.Sprintf("%s %s %s: %s\n", timeString, levelString, nameString, message)
+
+	fmt.Fprint(l.output.writer, logString)
+}
+
+// 调用方需要持有 output 的锁
+func (l *TinyLogger) logJSON(logLevel logLevel, now time.Time, message string) {
+	line, err := json.Marshal(jsonLogLine{
+		Time:  now.Format(time.RFC3339Nano),
+		Level: logLevel.String(),
+		Name:  l.name,
+		Msg:   message,
+	})
+	if err != nil {
+		return
+	}
 
-	fmt.Print(logString)
+	l.output.writer.Write(append(line, '\n'))
 }
 
 func (l *TinyLogger) Tracef(format string, args ...any) {
@@ -0,0 +1,112 @@
+package emi_transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testLogTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestTinyLoggerText(t *testing.T) {
+	logger, buffer := newTestLogger()
+	logger.SetNowFunc(func() time.Time { return testLogTime })
+
+	logger.Infof("hello %s\n", "emi")
+
+	want := "[2026-01-02 03:04:05]  [INFO] [test]: hello emi\n"
+	if got := buffer.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestTinyLoggerColor(t *testing.T) {
+	logger, buffer := newTestLogger()
+
+	logger.Warnf("plain")
+	if strings.Contains(buffer.String(), "\033[") {
+		t.Errorf("ColorAuto colored output to a non-terminal: %q", buffer.String())
+	}
+
+	logger.SetColorMode(ColorAlways)
+	logger.Warnf("colored")
+	if !strings.Contains(buffer.String(), logLevelWarn.color()+" [WARN]"+colorReset) {
+		t.Errorf("ColorAlways did not color the level: %q", buffer.String())
+	}
+}
+
+func TestTinyLoggerJSON(t *testing.T) {
+	logger, buffer := newTestLogger()
+	logger.SetNowFunc(func() time.Time { return testLogTime })
+	logger.SetFormat(LogFormatJSON)
+
+	logger.Errorf("failed: %d", 42)
+
+	line := jsonLogLine{}
+	if err := json.Unmarshal([]byte(buffer.String()), &line); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	want := jsonLogLine{Time: "2026-01-02T03:04:05Z", Level: "ERROR", Name: "test", Msg: "failed: 42"}
+	if line != want {
+		t.Errorf("line = %+v, want %+v", line, want)
+	}
+}
+
+func TestTinyLoggerNamed(t *testing.T) {
+	parent := NewTinyLogger("emi")
+	child := parent.Named("http")
+
+	// 派生之后修改父日志器的设置同样作用于子日志器
+	logs := &syncBuffer{}
+	parent.SetWriter(logs)
+	parent.SetNowFunc(func() time.Time { return testLogTime })
+
+	child.Infof("request sent")
+	parent.Infof("started")
+
+	output := logs.String()
+	if !strings.Contains(output, "[emi.http]: request sent") {
+		t.Errorf("child output missing or unprefixed:\n%s", output)
+	}
+	if !strings.Contains(output, "[emi]: started") {
+		t.Errorf("parent output missing:\n%s", output)
+	}
+	if !strings.Contains(output, "[2026-01-02 03:04:05]") {
+		t.Errorf("child did not use the parent's clock:\n%s", output)
+	}
+}
+
+func TestTinyLoggerConcurrentWrites(t *testing.T) {
+	// 不可并发写入的 writer，-race 下能发现并发写入
+	buffer := &bytes.Buffer{}
+	logger := NewTinyLogger("test")
+	logger.SetWriter(buffer)
+	child := logger.Named("child")
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				logger.Infof("parent %d", j)
+				child.Infof("child %d", j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 8*50*2 {
+		t.Fatalf("got %d lines, want %d", len(lines), 8*50*2)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "[") || strings.Count(line, "[INFO]") != 1 {
+			t.Errorf("interleaved line: %q", line)
+			break
+		}
+	}
+}
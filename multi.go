@@ -0,0 +1,150 @@
+package emi_transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 把多个事件源（例如分片的 WebSocket 连接）的事件汇入同一个通道的事件源
+//
+// 所有事件源都关闭后输出通道才会关闭；某个事件源的通道关闭不影响其余事件源。
+type MultiEventSource struct {
+	sync.Mutex
+
+	logger Logger
+
+	sources []EventSource
+	opened  []EventSource
+
+	run *multiRun
+}
+
+// 一次 Open 到 Close 之间的转发状态
+type multiRun struct {
+	out    chan RawEvent
+	done   chan any
+	active int
+}
+
+var _ EventSource = (*MultiEventSource)(nil)
+
+func NewMultiEventSource(logger Logger, sources ...EventSource) *MultiEventSource {
+	return &MultiEventSource{
+		logger: logger,
+
+		sources: append([]EventSource(nil), sources...),
+	}
+}
+
+// 添加事件源，已开启时立即开启该事件源并汇入输出通道
+func (m *MultiEventSource) AddEventSource(ctx context.Context, source EventSource) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.sources = append(m.sources, source)
+
+	if m.run == nil {
+		return nil
+	}
+	return m.open(ctx, source)
+}
+
+// 开启全部事件源，任意一个开启失败时关闭已开启的事件源并返回错误
+func (m *MultiEventSource) Open(ctx context.Context) (chan RawEvent, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.run != nil {
+		return nil, ErrAlreadyConnected
+	}
+
+	m.run = &multiRun{
+		out:  make(chan RawEvent),
+		done: make(chan any),
+	}
+
+	for _, source := range m.sources {
+		if err := m.open(ctx, source); err != nil {
+			if closeErr := m.shutdown(); closeErr != nil {
+				m.logger.Errorf("Failed to close event sources: %v", closeErr)
+			}
+			return nil, err
+		}
+	}
+
+	return m.run.out, nil
+}
+
+// 关闭全部已开启的事件源
+func (m *MultiEventSource) Close() error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.run == nil {
+		return nil
+	}
+	return m.shutdown()
+}
+
+// 开启单个事件源并启动转发协程，调用方需要持有锁
+func (m *MultiEventSource) open(ctx context.Context, source EventSource) error {
+	in, err := source.Open(ctx)
+	if err != nil {
+		return err
+	}
+	m.opened = append(m.opened, source)
+
+	m.run.active++
+	go m.forward(m.run, in)
+
+	return nil
+}
+
+// 关闭已开启的事件源并停止转发，调用方需要持有锁
+func (m *MultiEventSource) shutdown() error {
+	errs := []error{}
+	for _, source := range m.opened {
+		if err := source.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	m.opened = nil
+
+	close(m.run.done)
+	if m.run.active == 0 {
+		close(m.run.out)
+	}
+	m.run = nil
+
+	return errors.Join(errs...)
+}
+
+func (m *MultiEventSource) forward(run *multiRun, in chan RawEvent) {
+	defer func() {
+		m.Lock()
+		defer m.Unlock()
+
+		// 最后一个退出的转发协程负责关闭输出通道
+		run.active--
+		if run.active > 0 {
+			return
+		}
+		close(run.out)
+
+		// 所有事件源都已自行关闭
+		if m.run == run {
+			close(run.done)
+			m.opened = nil
+			m.run = nil
+		}
+	}()
+
+	for event := range in {
+		select {
+		case run.out <- event:
+		case <-run.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package emi_transport
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMultiEventSourceFanIn(t *testing.T) {
+	first, second := &chanEventSource{}, &chanEventSource{}
+	logger, _ := newTestLogger()
+	m := NewMultiEventSource(logger, first, second)
+
+	out, err := m.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer m.Close()
+
+	first.send(t, RawEvent{Type: "from_first"})
+	if event := readEvent(t, out); event.Type != "from_first" {
+		t.Errorf("event type = %s, want from_first", event.Type)
+	}
+
+	// 一个事件源关闭不影响其余事件源
+	first.Close()
+	second.send(t, RawEvent{Type: "from_second"})
+	if event := readEvent(t, out); event.Type != "from_second" {
+		t.Errorf("event type = %s, want from_second", event.Type)
+	}
+
+	// 全部事件源关闭后输出通道关闭
+	second.Close()
+	waitClosed(t, out)
+}
+
+func TestMultiEventSourceAddWhileOpen(t *testing.T) {
+	first, added := &chanEventSource{}, &chanEventSource{}
+	logger, _ := newTestLogger()
+	m := NewMultiEventSource(logger, first)
+
+	out, err := m.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer m.Close()
+
+	if err := m.AddEventSource(context.Background(), added); err != nil {
+		t.Fatalf("AddEventSource returned %v", err)
+	}
+	added.send(t, RawEvent{Type: "from_added"})
+	if event := readEvent(t, out); event.Type != "from_added" {
+		t.Errorf("event type = %s, want from_added", event.Type)
+	}
+}
+
+func TestMultiEventSourceReopen(t *testing.T) {
+	source := &chanEventSource{}
+	logger, _ := newTestLogger()
+	m := NewMultiEventSource(logger, source)
+
+	out, err := m.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	if _, err := m.Open(context.Background()); err != ErrAlreadyConnected {
+		t.Errorf("second Open returned %v, want ErrAlreadyConnected", err)
+	}
+
+	// 不消费 out，转发协程阻塞在发送上时 Close 也不阻塞
+	source.send(t, RawEvent{Type: "unread"})
+	if err := m.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+	waitClosed(t, out)
+
+	out, err = m.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open after Close returned %v", err)
+	}
+	defer m.Close()
+
+	source.send(t, RawEvent{Type: "after_reopen"})
+	if event := readEvent(t, out); event.Type != "after_reopen" {
+		t.Errorf("event type = %s, want after_reopen", event.Type)
+	}
+}
@@ -0,0 +1,143 @@
+package emi_transport
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	emi_core "github.com/aK1r4z/emi-core"
+)
+
+// 默认最多记录的表情回应数
+const defaultReactionTrackLimit = 4096
+
+type reactionKey struct {
+	groupID    int64
+	messageSeq int64
+	reaction   string
+}
+
+// 记录通过 ToggleGroupMessageReaction 添加的表情回应
+type reactionTracker struct {
+	sync.Mutex
+
+	// 已添加的回应，order 按添加顺序排列，超出 limit 时淘汰最早添加的记录
+	added map[reactionKey]*list.Element
+	order *list.List
+	limit int
+
+	// 正在切换的回应，切换完成时关闭通道
+	inFlight map[reactionKey]chan struct{}
+}
+
+// 设置最多记录的表情回应数，超出时淘汰最早添加的记录，不大于 0 时使用默认值
+//
+// 被淘汰的回应再次切换时会被视为尚未添加。
+func (h *HttpClient) SetReactionTrackLimit(limit int) {
+	h.reactions.Lock()
+	defer h.reactions.Unlock()
+
+	h.reactions.limit = limit
+	h.reactions.evict()
+}
+
+// 切换群消息的表情回应
+//
+// 该消息尚未被本客户端添加此回应时添加，已添加时移除，返回切换后是否处于已添加状态。
+// 状态只记录通过本方法完成的切换，不会同步其他来源的回应；
+// 同一回应的并发切换依次进行，每次切换都基于上一次的结果。
+func (h *HttpClient) ToggleGroupMessageReaction(ctx context.Context, groupID int64, messageSeq int64, reaction string) (bool, error) {
+	key := reactionKey{
+		groupID:    groupID,
+		messageSeq: messageSeq,
+		reaction:   reaction,
+	}
+
+	added, err := h.reactions.acquire(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = h.SendGroupMessageReaction(ctx, emi_core.SendGroupMessageReactionRequest{
+		GroupID:    groupID,
+		MessageSeq: messageSeq,
+		Reaction:   reaction,
+		IsAdd:      !added,
+	})
+	h.reactions.release(key, added, err == nil)
+	if err != nil {
+		return added, err
+	}
+
+	return !added, nil
+}
+
+// 等待同一回应的其他切换完成并把该回应标记为正在切换，返回当前是否处于已添加状态
+func (t *reactionTracker) acquire(ctx context.Context, key reactionKey) (bool, error) {
+	for {
+		t.Lock()
+		wait, busy := t.inFlight[key]
+		if !busy {
+			if t.inFlight == nil {
+				t.inFlight = map[reactionKey]chan struct{}{}
+			}
+			t.inFlight[key] = make(chan struct{})
+			_, added := t.added[key]
+			t.Unlock()
+			return added, nil
+		}
+		t.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// 结束切换，toggled 为 true 时翻转记录的状态
+func (t *reactionTracker) release(key reactionKey, added bool, toggled bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	close(t.inFlight[key])
+	delete(t.inFlight, key)
+
+	if !toggled {
+		return
+	}
+
+	if added {
+		if element, ok := t.added[key]; ok {
+			t.order.Remove(element)
+			delete(t.added, key)
+		}
+		return
+	}
+
+	if t.added == nil {
+		t.added = map[reactionKey]*list.Element{}
+		t.order = list.New()
+	}
+	t.added[key] = t.order.PushBack(key)
+	t.evict()
+}
+
+// 淘汰超出上限的记录，调用方需要持有锁
+func (t *reactionTracker) evict() {
+	if t.order == nil {
+		return
+	}
+
+	limit := t.limit
+	if limit <= 0 {
+		limit = defaultReactionTrackLimit
+	}
+
+	for t.order.Len() > limit {
+		oldest := t.order.Front()
+		t.order.Remove(oldest)
+		delete(t.added, oldest.Value.(reactionKey))
+	}
+}
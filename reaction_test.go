@@ -0,0 +1,151 @@
+package emi_transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	emi_core "github.com/aK1r4z/emi-core"
+)
+
+func TestToggleGroupMessageReaction(t *testing.T) {
+	mu := sync.Mutex{}
+	requests := []emi_core.SendGroupMessageReactionRequest{}
+	fail := false
+
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		request := emi_core.SendGroupMessageReactionRequest{}
+		json.NewDecoder(r.Body).Decode(&request)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, request)
+
+		if fail {
+			json.NewEncoder(w).Encode(HttpResult{Status: "failed", Code: 1400, Message: "denied"})
+			return
+		}
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+	ctx := context.Background()
+
+	toggle := func(reaction string, wantAdded bool) {
+		t.Helper()
+
+		added, err := h.ToggleGroupMessageReaction(ctx, 100, 5, reaction)
+		if err != nil {
+			t.Fatalf("ToggleGroupMessageReaction returned %v", err)
+		}
+		if added != wantAdded {
+			t.Errorf("%s: added = %v, want %v", reaction, added, wantAdded)
+		}
+	}
+
+	toggle("76", true)
+	toggle("76", false)
+	toggle("76", true)
+	toggle("66", true)
+
+	// 失败时不改变记录的状态
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	if added, err := h.ToggleGroupMessageReaction(ctx, 100, 5, "66"); err == nil || !added {
+		t.Errorf("failed toggle = %v, %v, want the previous state and an error", added, err)
+	}
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	toggle("66", false)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []bool{true, false, true, true, false, false}
+	if len(requests) != len(want) {
+		t.Fatalf("sent %d requests, want %d", len(requests), len(want))
+	}
+	for i, isAdd := range want {
+		if requests[i].IsAdd != isAdd || requests[i].GroupID != 100 || requests[i].MessageSeq != 5 {
+			t.Errorf("request %d = %+v, want IsAdd %v", i, requests[i], isAdd)
+		}
+	}
+}
+
+func TestToggleGroupMessageReactionConcurrent(t *testing.T) {
+	received := make(chan bool, 2)
+	release := make(chan struct{})
+
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		request := emi_core.SendGroupMessageReactionRequest{}
+		json.NewDecoder(r.Body).Decode(&request)
+		received <- request.IsAdd
+
+		<-release
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+	ctx := context.Background()
+
+	results := make(chan bool, 2)
+	toggle := func() {
+		added, err := h.ToggleGroupMessageReaction(ctx, 100, 5, "76")
+		if err != nil {
+			t.Errorf("ToggleGroupMessageReaction returned %v", err)
+		}
+		results <- added
+	}
+
+	go toggle()
+	if isAdd := <-received; !isAdd {
+		t.Errorf("first toggle sent IsAdd false")
+	}
+
+	// 第二次切换等待第一次完成后才发送请求
+	go toggle()
+	select {
+	case <-received:
+		t.Fatal("second toggle sent a request while the first was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if isAdd := <-received; isAdd {
+		t.Errorf("second toggle sent IsAdd true, want it to remove the reaction")
+	}
+
+	// 两次切换的结果分别为添加与移除
+	if first, second := <-results, <-results; first == second {
+		t.Errorf("results = %v, %v, want one add and one remove", first, second)
+	}
+}
+
+func TestToggleGroupMessageReactionTrackLimit(t *testing.T) {
+	requests := make(chan bool, 8)
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		request := emi_core.SendGroupMessageReactionRequest{}
+		json.NewDecoder(r.Body).Decode(&request)
+		requests <- request.IsAdd
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+	h.SetReactionTrackLimit(2)
+	ctx := context.Background()
+
+	for _, reaction := range []string{"1", "2", "3"} {
+		h.ToggleGroupMessageReaction(ctx, 100, 5, reaction)
+		<-requests
+	}
+
+	// "1" 已被淘汰，再次切换视为添加；"3" 仍被记录
+	if added, _ := h.ToggleGroupMessageReaction(ctx, 100, 5, "1"); !added || !<-requests {
+		t.Errorf("evicted reaction was not added again")
+	}
+	if added, _ := h.ToggleGroupMessageReaction(ctx, 100, 5, "3"); added || <-requests {
+		t.Errorf("tracked reaction was not removed")
+	}
+	if size := len(h.reactions.added); size > 2 {
+		t.Errorf("tracked %d reactions, want at most 2", size)
+	}
+}
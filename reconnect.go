@@ -0,0 +1,204 @@
+package emi_transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 断线重连的默认退避时间
+const (
+	defaultReconnectBaseDelay = time.Second
+	defaultReconnectMaxDelay  = 30 * time.Second
+)
+
+// 一次重连尝试的信息
+type ReconnectInfo struct {
+	Reason   error         // 触发重连的错误
+	Attempt  int           // 第几次尝试，从 1 开始
+	Downtime time.Duration // 从连接断开到本次尝试结束经过的时间
+	Err      error         // 本次尝试的错误，成功时为 nil
+}
+
+// 设置断线重连
+//
+// maxAttempts 为 0 时不重连（默认），小于 0 时不限次数；
+// 两次尝试之间的等待时间从 baseDelay 开始指数增长，最长为 maxDelay，不大于 0 时使用默认值。
+func (w *WebsocketEventSource) SetReconnect(maxAttempts int, baseDelay time.Duration, maxDelay time.Duration) {
+	w.Lock()
+	defer w.Unlock()
+
+	if baseDelay <= 0 {
+		baseDelay = defaultReconnectBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultReconnectMaxDelay
+	}
+
+	w.maxReconnectAttempts = maxAttempts
+	w.reconnectBaseDelay = baseDelay
+	w.reconnectMaxDelay = max(maxDelay, baseDelay)
+}
+
+// 设置重连成功后的回调，首次连接时不会调用，可用于重新同步群列表等状态
+//
+// 回调在独立的协程中执行，不会阻塞事件接收。
+func (w *WebsocketEventSource) OnReconnect(hook func()) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.onReconnect = hook
+}
+
+// 设置首次连接失败时的重试次数，用于协议端与机器人同时启动、网关尚未就绪的情况
+//
+// maxAttempts 为 0 时不重试（默认），小于 0 时不限次数，直到 ctx 被取消；
+// 等待时间与断线重连相同，由 SetReconnect 的 baseDelay 与 maxDelay 决定。
+func (w *WebsocketEventSource) SetOpenRetry(maxAttempts int) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.maxOpenAttempts = maxAttempts
+}
+
+// 设置重连钩子，每次重连尝试结束后调用，可用于监控与告警
+func (w *WebsocketEventSource) SetReconnectHook(hook func(ReconnectInfo)) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.reconnectHook = hook
+}
+
+// 第 attempt 次重连前的等待时间
+func (w *WebsocketEventSource) reconnectDelay(attempt int) time.Duration {
+	delay := w.reconnectBaseDelay
+	for i := 1; i < attempt && delay < w.reconnectMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, w.reconnectMaxDelay)
+}
+
+// 首次连接，失败时按照 SetOpenRetry 的设置重试，调用方不能持有锁
+func (w *WebsocketEventSource) dialWithRetry(ctx context.Context, dialer websocket.Dialer, gateway string, header http.Header) (*websocket.Conn, error) {
+	for attempt := 1; ; attempt++ {
+		wsConn, err := dialWebsocket(ctx, dialer, gateway, header)
+		if err == nil {
+			return wsConn, nil
+		}
+
+		w.RLock()
+		maxAttempts := w.maxOpenAttempts
+		delay := w.reconnectDelay(attempt)
+		clock := w.clock
+		w.RUnlock()
+
+		if ctx.Err() != nil || (maxAttempts >= 0 && attempt > maxAttempts) {
+			return nil, err
+		}
+
+		w.logger.Warnf("Failed to connect to websocket gateway, retrying after %s (attempt %d): %v", delay, attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w (last error: %w)", ctx.Err(), err)
+		case <-clock.After(delay):
+		}
+	}
+}
+
+// 尝试重新建立连接
+//
+// 成功时返回新的连接；未开启重连、重连次数耗尽或连接已被关闭时返回 nil。
+func (w *WebsocketEventSource) reconnect(oldConn *websocket.Conn, reason error, closeChan chan any) *websocket.Conn {
+	w.RLock()
+	maxAttempts := w.maxReconnectAttempts
+	clock := w.clock
+	w.RUnlock()
+
+	if maxAttempts == 0 {
+		return nil
+	}
+
+	w.connected.Store(false)
+	oldConn.Close()
+	disconnectedAt := clock.Now()
+
+	// 连接被关闭时取消重连
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-closeChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for attempt := 1; maxAttempts < 0 || attempt <= maxAttempts; attempt++ {
+		w.RLock()
+		delay := w.reconnectDelay(attempt)
+		w.RUnlock()
+
+		w.logger.Infof("Reconnecting to websocket gateway after %s (attempt %d)", delay, attempt)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-clock.After(delay):
+		}
+
+		w.RLock()
+		dialer, gateway, header, err := w.dialConfig()
+		hook := w.reconnectHook
+		w.RUnlock()
+
+		var wsConn *websocket.Conn
+		if err == nil {
+			wsConn, err = dialWebsocket(ctx, dialer, gateway, header)
+		}
+
+		if ctx.Err() != nil {
+			if wsConn != nil {
+				wsConn.Close()
+			}
+			return nil
+		}
+
+		if hook != nil {
+			hook(ReconnectInfo{
+				Reason:   reason,
+				Attempt:  attempt,
+				Downtime: clock.Now().Sub(disconnectedAt),
+				Err:      err,
+			})
+		}
+
+		if err != nil {
+			w.logger.Errorf("Failed to reconnect to websocket gateway: %v", err)
+			continue
+		}
+
+		// 替换为新的连接，期间连接被关闭则放弃
+		w.Lock()
+		if w.wsConn != oldConn {
+			w.Unlock()
+			wsConn.Close()
+			return nil
+		}
+		w.wsConn = wsConn
+		w.connected.Store(true)
+		onReconnect := w.onReconnect
+		w.Unlock()
+
+		w.logger.Infof("Reconnected to websocket gateway after %d attempts", attempt)
+		if onReconnect != nil {
+			go onReconnect()
+		}
+		return wsConn
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package emi_transport
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// After 返回的通道由测试控制的时钟，用于观察重连等待期间的状态
+type gatedClock struct {
+	*fakeClock
+
+	gate chan time.Time
+}
+
+func (c *gatedClock) After(d time.Duration) <-chan time.Time {
+	c.fakeClock.After(d)
+	return c.gate
+}
+
+func newGatedClock() *gatedClock {
+	return &gatedClock{
+		fakeClock: newFakeClock(),
+		gate:      make(chan time.Time),
+	}
+}
+
+func TestWebsocketReconnect(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+
+	clock := newGatedClock()
+	w.SetClock(clock)
+	w.SetReconnect(3, time.Second, 10*time.Second)
+
+	infos := make(chan ReconnectInfo, 8)
+	w.SetReconnectHook(func(info ReconnectInfo) { infos <- info })
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	first := server.accept(t)
+
+	// 服务端断开连接后等待重连
+	first.Close()
+	clock.gate <- time.Now()
+	second := server.accept(t)
+
+	writeEvent(t, second, RawEvent{Type: "after_reconnect"})
+	if event := readEvent(t, eventChan); event.Type != "after_reconnect" {
+		t.Errorf("event type = %s, want after_reconnect", event.Type)
+	}
+
+	info := <-infos
+	if info.Attempt != 1 || info.Err != nil || info.Reason == nil || info.Downtime != time.Second {
+		t.Errorf("ReconnectInfo = %+v, want attempt 1 after 1s without error", info)
+	}
+	if sleeps := clock.Sleeps(); len(sleeps) != 1 || sleeps[0] != time.Second {
+		t.Errorf("reconnect delays = %v, want [1s]", sleeps)
+	}
+}
+
+func TestWebsocketNoReconnectByDefault(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+
+	server.accept(t).Close()
+	waitClosed(t, eventChan)
+
+	if handshakes := server.handshakes.Load(); handshakes != 1 {
+		t.Errorf("handshakes = %d, want 1 without reconnect", handshakes)
+	}
+}
+
+func TestWebsocketHealthDuringReconnect(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+
+	clock := newGatedClock()
+	w.SetClock(clock)
+	w.SetReconnect(3, time.Second, 10*time.Second)
+
+	if _, err := w.Open(context.Background()); err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	first := server.accept(t)
+
+	if health := w.Health(); !health.Connected {
+		t.Errorf("Health reports disconnected after Open")
+	}
+
+	// 等待重连期间报告未连接
+	first.Close()
+	eventually(t, "Connected to become false", func() bool { return !w.Health().Connected })
+
+	clock.gate <- time.Now()
+	server.accept(t)
+	eventually(t, "Connected to become true", func() bool { return w.Health().Connected })
+
+	w.Close()
+	if health := w.Health(); health.Connected {
+		t.Errorf("Health reports connected after Close")
+	}
+}
+
+func TestWebsocketOnReconnect(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+
+	clock := newGatedClock()
+	w.SetClock(clock)
+	w.SetReconnect(3, time.Second, 10*time.Second)
+
+	reconnects := atomic.Int32{}
+	w.OnReconnect(func() { reconnects.Add(1) })
+
+	if _, err := w.Open(context.Background()); err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	first := server.accept(t)
+
+	// 首次连接不触发
+	time.Sleep(20 * time.Millisecond)
+	if reconnects.Load() != 0 {
+		t.Errorf("OnReconnect fired on the initial open")
+	}
+
+	first.Close()
+	clock.gate <- time.Now()
+	server.accept(t)
+
+	eventually(t, "OnReconnect", func() bool { return reconnects.Load() == 1 })
+}
+
+func TestWebsocketOpenRetry(t *testing.T) {
+	server := newWSTestServer(t)
+	server.reject.Store(2)
+
+	w := newTestWebsocketEventSource(server.gateway())
+	clock := newFakeClock()
+	w.SetClock(clock)
+	w.SetReconnect(0, time.Second, 10*time.Second)
+	w.SetOpenRetry(2)
+
+	if _, err := w.Open(context.Background()); err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	server.accept(t)
+
+	if handshakes := server.handshakes.Load(); handshakes != 3 {
+		t.Errorf("handshakes = %d, want 3", handshakes)
+	}
+	if sleeps := clock.Sleeps(); len(sleeps) != 2 || sleeps[0] != time.Second || sleeps[1] != 2*time.Second {
+		t.Errorf("open retry delays = %v, want [1s 2s]", sleeps)
+	}
+}
+
+func TestWebsocketOpenRetryExhausted(t *testing.T) {
+	server := newWSTestServer(t)
+	server.reject.Store(3)
+
+	w := newTestWebsocketEventSource(server.gateway())
+	w.SetClock(newFakeClock())
+	w.SetOpenRetry(1)
+
+	_, err := w.Open(context.Background())
+
+	handshakeErr := &WSHandshakeError{}
+	if !errors.As(err, &handshakeErr) {
+		t.Fatalf("Open returned %T %v, want *WSHandshakeError", err, err)
+	}
+	if handshakes := server.handshakes.Load(); handshakes != 2 {
+		t.Errorf("handshakes = %d, want 2", handshakes)
+	}
+}
+
+func TestWebsocketCloseCancelsPendingOpen(t *testing.T) {
+	server := newWSTestServer(t)
+	server.reject.Store(1 << 30)
+
+	w := newTestWebsocketEventSource(server.gateway())
+	w.SetReconnect(0, time.Hour, time.Hour)
+	w.SetOpenRetry(-1)
+
+	openErr := make(chan error, 1)
+	go func() {
+		_, err := w.Open(context.Background())
+		openErr <- err
+	}()
+	eventually(t, "the first handshake", func() bool { return server.handshakes.Load() >= 1 })
+
+	// 重试等待期间不持有锁，其余方法不被阻塞
+	done := make(chan struct{})
+	go func() {
+		w.Health()
+		w.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Health and Close blocked by a pending Open")
+	}
+
+	select {
+	case err := <-openErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Open returned %v after Close, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Open did not return after Close")
+	}
+}
+
+func TestWebsocketReadTimeoutAfterReconnect(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+	w.SetClock(newFakeClock())
+	w.SetReconnect(-1, time.Second, time.Second)
+	w.SetReadTimeout(50 * time.Millisecond)
+
+	if _, err := w.Open(context.Background()); err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+
+	// 服务端不发送任何数据，每个连接都应因读取超时而重连
+	for i := 0; i < 3; i++ {
+		server.accept(t)
+	}
+}
@@ -0,0 +1,180 @@
+package emi_transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// 把事件源的每个事件追加写入 JSONL 文件的事件源，便于事后排查与回放
+//
+// 文件超过 maxBytes 字节时轮转：当前文件重命名为 path + ".1"（覆盖旧的备份），然后重新创建。
+type RecordingEventSource struct {
+	sync.Mutex
+
+	logger Logger
+
+	source EventSource
+
+	path     string
+	maxBytes int64
+
+	file *os.File
+	size int64
+
+	done chan any
+}
+
+var _ EventSource = (*RecordingEventSource)(nil)
+
+// maxBytes 不大于 0 时不轮转
+func NewRecordingEventSource(logger Logger, source EventSource, path string, maxBytes int64) *RecordingEventSource {
+	return &RecordingEventSource{
+		logger: logger,
+
+		source: source,
+
+		path:     path,
+		maxBytes: maxBytes,
+	}
+}
+
+// 开启
+func (r *RecordingEventSource) Open(ctx context.Context) (chan RawEvent, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.file != nil {
+		return nil, ErrAlreadyConnected
+	}
+
+	if err := r.openFile(); err != nil {
+		return nil, err
+	}
+
+	in, err := r.source.Open(ctx)
+	if err != nil {
+		r.closeFile()
+		return nil, err
+	}
+
+	r.done = make(chan any)
+
+	out := make(chan RawEvent)
+	go r.forward(in, out, r.done)
+
+	return out, nil
+}
+
+// 关闭
+func (r *RecordingEventSource) Close() error {
+	r.Lock()
+	if r.done != nil {
+		close(r.done)
+		r.done = nil
+	}
+	r.closeFile()
+	r.Unlock()
+
+	return r.source.Close()
+}
+
+func (r *RecordingEventSource) forward(in chan RawEvent, out chan RawEvent, done chan any) {
+	defer close(out)
+	defer func() {
+		r.Lock()
+		defer r.Unlock()
+
+		// 事件源自行关闭时关闭文件；已经 Close 并重新 Open 时文件属于新的转发协程
+		if r.done == done {
+			r.done = nil
+			r.closeFile()
+		}
+	}()
+
+	for event := range in {
+		if err := r.record(done, event); err != nil {
+			r.logger.Errorf("Failed to record event: %v", err)
+		}
+
+		select {
+		case out <- event:
+		case <-done:
+			return
+		}
+	}
+}
+
+// 写入一行事件
+func (r *RecordingEventSource) record(done chan any, event RawEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	line = append(line, '\n')
+
+	r.Lock()
+	defer r.Unlock()
+
+	if r.done != done || r.file == nil {
+		return nil
+	}
+
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(line)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+
+	return nil
+}
+
+// 轮转文件，调用方需要持有锁
+func (r *RecordingEventSource) rotate() error {
+	r.closeFile()
+
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate record file: %w", err)
+	}
+
+	return r.openFile()
+}
+
+// 打开文件，调用方需要持有锁
+func (r *RecordingEventSource) openFile() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open record file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat record file: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+
+	return nil
+}
+
+// 关闭文件，调用方需要持有锁
+func (r *RecordingEventSource) closeFile() {
+	if r.file == nil {
+		return
+	}
+
+	if err := r.file.Close(); err != nil {
+		r.logger.Errorf("Failed to close record file: %v", err)
+	}
+	r.file = nil
+}
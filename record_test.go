@@ -0,0 +1,96 @@
+package emi_transport
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordingEventSourceWritesJSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	source := &chanEventSource{}
+	logger, _ := newTestLogger()
+	r := NewRecordingEventSource(logger, source, path, 0)
+
+	out, err := r.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+
+	for _, event := range []RawEvent{{Type: "e0", SelfID: 1}, {Type: "e1", SelfID: 1}} {
+		source.send(t, event)
+		readEvent(t, out)
+	}
+	r.Close()
+	waitClosed(t, out)
+
+	events, err := readEvents(path)
+	if err != nil {
+		t.Fatalf("readEvents returned %v", err)
+	}
+	if len(events) != 2 || events[0].Type != "e0" || events[1].Type != "e1" {
+		t.Errorf("recorded events = %+v, want e0 and e1", events)
+	}
+}
+
+func TestRecordingEventSourceRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	source := &chanEventSource{}
+	logger, _ := newTestLogger()
+
+	// 每个文件只能容纳一个事件
+	r := NewRecordingEventSource(logger, source, path, 32)
+
+	out, err := r.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer r.Close()
+
+	for _, event := range []RawEvent{{Type: "e0"}, {Type: "e1"}, {Type: "e2"}} {
+		source.send(t, event)
+		readEvent(t, out)
+	}
+
+	current, err := readEvents(path)
+	if err != nil || len(current) != 1 || current[0].Type != "e2" {
+		t.Errorf("current file = %+v, %v, want e2", current, err)
+	}
+	backup, err := readEvents(path + ".1")
+	if err != nil || len(backup) != 1 || backup[0].Type != "e1" {
+		t.Errorf("backup file = %+v, %v, want e1", backup, err)
+	}
+}
+
+func TestRecordingEventSourceReopenWithoutConsumer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	source := &chanEventSource{}
+	logger, _ := newTestLogger()
+	r := NewRecordingEventSource(logger, source, path, 0)
+
+	out, err := r.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+
+	// 不消费 out，转发协程阻塞在发送上
+	source.send(t, RawEvent{Type: "e0"})
+	r.Close()
+	waitClosed(t, out)
+
+	out, err = r.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open after Close returned %v", err)
+	}
+	source.send(t, RawEvent{Type: "e1"})
+	readEvent(t, out)
+	r.Close()
+
+	events, err := readEvents(path)
+	if err != nil {
+		t.Fatalf("readEvents returned %v", err)
+	}
+	if len(events) != 2 || events[0].Type != "e0" || events[1].Type != "e1" {
+		t.Errorf("recorded events = %+v, want e0 and e1", events)
+	}
+}
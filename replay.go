@@ -0,0 +1,176 @@
+package emi_transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// 从 JSONL 文件回放事件的事件源，文件格式与 RecordingEventSource 写入的相同，可用于压力测试
+//
+// 文件在 Open 时被完整读入内存。默认以最快速度回放一遍后关闭事件通道。
+type FileReplayEventSource struct {
+	sync.Mutex
+
+	logger Logger
+
+	path string
+
+	speed float64
+	loop  bool
+
+	clock Clock
+
+	cancel context.CancelFunc
+}
+
+var _ EventSource = (*FileReplayEventSource)(nil)
+
+func NewFileReplayEventSource(logger Logger, path string) *FileReplayEventSource {
+	return &FileReplayEventSource{
+		logger: logger,
+
+		path: path,
+
+		clock: realClock{},
+	}
+}
+
+// 设置回放速度，按照事件的 Time 字段计算间隔，1 为实时回放，2 为两倍速
+//
+// 不大于 0 时以最快速度回放（默认）。在下一次 Open 时生效。
+func (f *FileReplayEventSource) SetSpeed(speed float64) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.speed = speed
+}
+
+// 设置是否循环回放，循环回放时直到 Close 才会关闭事件通道
+func (f *FileReplayEventSource) SetLoop(loop bool) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.loop = loop
+}
+
+// 设置回放计时所使用的时钟，为 nil 时使用系统时间
+func (f *FileReplayEventSource) SetClock(clock Clock) {
+	f.Lock()
+	defer f.Unlock()
+
+	if clock == nil {
+		clock = realClock{}
+	}
+	f.clock = clock
+}
+
+// 开启
+func (f *FileReplayEventSource) Open(ctx context.Context) (chan RawEvent, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.cancel != nil {
+		return nil, ErrAlreadyConnected
+	}
+
+	events, err := readEvents(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	replayCtx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+
+	out := make(chan RawEvent)
+	go f.replay(replayCtx, events, out, f.speed, f.loop, f.clock)
+
+	return out, nil
+}
+
+// 关闭
+func (f *FileReplayEventSource) Close() error {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.cancel != nil {
+		f.cancel()
+		f.cancel = nil
+	}
+
+	return nil
+}
+
+func (f *FileReplayEventSource) replay(
+	ctx context.Context,
+	events []RawEvent,
+	out chan RawEvent,
+	speed float64,
+	loop bool,
+	clock Clock,
+) {
+	defer close(out)
+
+	for {
+		for i, event := range events {
+			// 按照与上一个事件的时间间隔等待
+			if speed > 0 && i > 0 {
+				delay := time.Duration(float64(time.Duration(event.Time-events[i-1].Time)*time.Second) / speed)
+				if delay > 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-clock.After(delay):
+					}
+				}
+			}
+
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if !loop || len(events) == 0 {
+			return
+		}
+	}
+}
+
+// 读取 JSONL 文件中的全部事件，跳过空行
+func readEvents(path string) ([]RawEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay file: %w", err)
+	}
+	defer file.Close()
+
+	events := []RawEvent{}
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read replay file: %w", err)
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			event := RawEvent{}
+			if err := json.Unmarshal(trimmed, &event); err != nil {
+				return nil, fmt.Errorf("failed to decode event on line %d: %w", lineNumber, err)
+			}
+			events = append(events, event)
+		}
+
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+	}
+}
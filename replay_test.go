@@ -0,0 +1,107 @@
+package emi_transport
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 写入回放文件，包含一个空行
+func writeReplayFile(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	content := strings.Join(lines, "\n") + "\n\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var replayLines = []string{
+	`{"event_type":"e0","time":1700000000}`,
+	`{"event_type":"e1","time":1700000002}`,
+	`{"event_type":"e2","time":1700000006}`,
+}
+
+func TestFileReplayEventSourceReplaysInOrder(t *testing.T) {
+	logger, _ := newTestLogger()
+	f := NewFileReplayEventSource(logger, writeReplayFile(t, replayLines...))
+
+	out, err := f.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer f.Close()
+
+	types := []string{}
+	for event := range out {
+		types = append(types, string(event.Type))
+	}
+	if want := []string{"e0", "e1", "e2"}; !slices.Equal(types, want) {
+		t.Errorf("replayed %v, want %v", types, want)
+	}
+}
+
+func TestFileReplayEventSourceSpeed(t *testing.T) {
+	logger, _ := newTestLogger()
+	f := NewFileReplayEventSource(logger, writeReplayFile(t, replayLines...))
+	clock := newFakeClock()
+	f.SetClock(clock)
+	f.SetSpeed(2)
+
+	out, err := f.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer f.Close()
+
+	for range out {
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second}
+	if sleeps := clock.Sleeps(); !slices.Equal(sleeps, want) {
+		t.Errorf("replay delays = %v, want %v", sleeps, want)
+	}
+}
+
+func TestFileReplayEventSourceLoop(t *testing.T) {
+	logger, _ := newTestLogger()
+	f := NewFileReplayEventSource(logger, writeReplayFile(t, replayLines...))
+	f.SetLoop(true)
+
+	out, err := f.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+
+	// 读完一遍后从头继续
+	types := []string{"e0", "e1", "e2"}
+	for i := 0; i < 7; i++ {
+		if event, want := readEvent(t, out), types[i%len(types)]; string(event.Type) != want {
+			t.Errorf("event %d type = %s, want %s", i, event.Type, want)
+		}
+	}
+
+	f.Close()
+	waitClosed(t, out)
+
+	if _, err := f.Open(context.Background()); err != nil {
+		t.Errorf("Open after Close returned %v", err)
+	}
+	f.Close()
+}
+
+func TestFileReplayEventSourceBadLine(t *testing.T) {
+	logger, _ := newTestLogger()
+	f := NewFileReplayEventSource(logger, writeReplayFile(t, replayLines[0], "not json"))
+
+	_, err := f.Open(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Open returned %v, want a decode error on line 2", err)
+	}
+}
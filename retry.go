@@ -0,0 +1,184 @@
+package emi_transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// 重试退避的抖动策略
+type JitterStrategy int
+
+const (
+	AdditiveJitter JitterStrategy = 0 + iota // 在指数退避上叠加 [0, MaxJitter) 的抖动（默认）
+	NoJitter                                 // 不添加抖动
+	FullJitter                               // 在 [0, delay] 内随机
+	EqualJitter                              // 在 [delay/2, delay] 内随机
+)
+
+// 重试策略
+type RetryPolicy struct {
+	MaxRetries int // 最大重试次数，为 0 时不重试，总尝试次数为 MaxRetries + 1
+
+	BaseDelay time.Duration // 第一次重试前的等待时间，此后指数增长
+	MaxDelay  time.Duration // 最长等待时间
+	MaxJitter time.Duration // AdditiveJitter 策略下叠加的最大抖动
+
+	Jitter JitterStrategy // 抖动策略
+
+	// 整个重试过程的最长耗时，等待下一次重试会超出该时长时不再重试，不大于 0 时不限制
+	MaxElapsed time.Duration
+
+	// 自定义重试判断，返回是否重试以及重试前的等待时间，设置后替代上面的重试次数与退避逻辑
+	ShouldRetry func(attempt int, err error) (bool, time.Duration)
+
+	// 每次重试等待之前调用，可用于日志与统计
+	OnRetry func(attempt int, delay time.Duration, err error)
+
+	Clock      Clock               // 为 nil 时使用系统时间
+	RandInt64N func(n int64) int64 // 返回 [0, n) 内的随机数，为 nil 时使用全局随机源
+}
+
+// 不应重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// 把错误标记为不可重试，Retry 遇到该错误时立即返回原始错误
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// 按照重试策略执行 fn，直到成功、遇到不可重试的错误、重试次数耗尽或 ctx 被取消
+//
+// 重试次数耗尽时返回 *RetryExhaustedError；等待重试期间 ctx 被取消时，
+// 返回的错误同时包装 ctx.Err() 与最后一次尝试的错误。
+func Retry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	clock := policy.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	start := clock.Now()
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		permanent := &permanentError{}
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+
+		// 请求失败，判断是否重试
+		retry, delay := policy.decide(attempt, err)
+
+		// 超出 MaxElapsed 而停止时总是返回 *RetryExhaustedError，即使只尝试了一次
+		elapsedExceeded := retry && policy.MaxElapsed > 0 && clock.Now().Sub(start)+delay > policy.MaxElapsed
+		if elapsedExceeded {
+			retry = false
+		}
+		if !retry {
+			if attempt == 0 && !elapsedExceeded {
+				return err
+			}
+			return &RetryExhaustedError{
+				Attempts: attempt + 1,
+				Elapsed:  clock.Now().Sub(start),
+				Last:     err,
+			}
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, delay, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			// 同时保留 ctx 的错误与最后一次尝试的错误，两者都可以被 errors.Is/errors.As 识别
+			return fmt.Errorf("retry aborted after %d attempts: %w (last error: %w)", attempt+1, ctx.Err(), err)
+		case <-clock.After(delay):
+		}
+	}
+}
+
+// 决定第 attempt 次尝试失败后是否重试，以及重试前的等待时间
+func (p RetryPolicy) decide(attempt int, err error) (bool, time.Duration) {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(attempt, err)
+	}
+
+	if attempt >= p.MaxRetries {
+		return false, 0
+	}
+	return true, p.Delay(attempt)
+}
+
+// 计算第 attempt 次重试前的等待时间，结果总在 [0, MaxDelay] 之内
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if p.MaxDelay <= 0 {
+		return 0
+	}
+	backoff := p.backoff(attempt)
+
+	switch p.Jitter {
+	case NoJitter:
+		return backoff
+	case FullJitter:
+		// [0, backoff]
+		return p.randDuration(backoff + 1)
+	case EqualJitter:
+		// [backoff/2, backoff]
+		half := backoff / 2
+		return half + p.randDuration(backoff-half+1)
+	default:
+		// 在指数退避的基础上叠加 [0, MaxJitter) 的随机抖动，超出 MaxDelay 的部分被截断
+		jitter := p.randDuration(p.MaxJitter)
+		if jitter > p.MaxDelay-backoff {
+			return p.MaxDelay
+		}
+		return backoff + jitter
+	}
+}
+
+// 计算不含抖动的指数退避 BaseDelay * 2^attempt，逐次翻倍并在达到 MaxDelay 时停止，避免移位溢出
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	backoff := p.BaseDelay
+	for i := 0; i < attempt; i++ {
+		if backoff > p.MaxDelay/2 {
+			return p.MaxDelay
+		}
+		backoff *= 2
+	}
+	return min(backoff, p.MaxDelay)
+}
+
+// 返回 [0, n) 内的随机时长，n 不为正数时返回 0
+func (p RetryPolicy) randDuration(n time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	if p.RandInt64N == nil {
+		return time.Duration(rand.Int64N(int64(n)))
+	}
+	return time.Duration(p.RandInt64N(int64(n)))
+}
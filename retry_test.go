@@ -0,0 +1,238 @@
+package emi_transport
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test error")
+
+func TestRetryPolicyDelayNoJitter(t *testing.T) {
+	policy := RetryPolicy{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+		Jitter:    NoJitter,
+	}
+
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for attempt, delay := range want {
+		if got := policy.Delay(attempt); got != delay {
+			t.Errorf("Delay(%d) = %s, want %s", attempt, got, delay)
+		}
+	}
+}
+
+func TestRetryPolicyDelayJitter(t *testing.T) {
+	maxRand := func(n int64) int64 { return n - 1 }
+	minRand := func(n int64) int64 { return 0 }
+
+	tests := []struct {
+		name   string
+		jitter JitterStrategy
+		rand   func(n int64) int64
+		want   time.Duration
+	}{
+		{"additive max", AdditiveJitter, maxRand, 400*time.Millisecond + 50*time.Millisecond - 1},
+		{"additive min", AdditiveJitter, minRand, 400 * time.Millisecond},
+		{"full max", FullJitter, maxRand, 400 * time.Millisecond},
+		{"full min", FullJitter, minRand, 0},
+		{"equal max", EqualJitter, maxRand, 400 * time.Millisecond},
+		{"equal min", EqualJitter, minRand, 200 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		policy := RetryPolicy{
+			BaseDelay:  100 * time.Millisecond,
+			MaxDelay:   time.Second,
+			MaxJitter:  50 * time.Millisecond,
+			Jitter:     test.jitter,
+			RandInt64N: test.rand,
+		}
+		if got := policy.Delay(2); got != test.want {
+			t.Errorf("%s: Delay(2) = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRetryPolicyDelayBounds(t *testing.T) {
+	strategies := []JitterStrategy{AdditiveJitter, NoJitter, FullJitter, EqualJitter}
+	attempts := []int{0, 1, 30, 62, 63, 64, 100, 1 << 20, math.MaxInt32}
+
+	for _, strategy := range strategies {
+		policy := RetryPolicy{
+			BaseDelay:  100 * time.Millisecond,
+			MaxDelay:   5 * time.Second,
+			MaxJitter:  time.Second,
+			Jitter:     strategy,
+			RandInt64N: func(n int64) int64 { return n - 1 },
+		}
+		for _, attempt := range attempts {
+			if delay := policy.Delay(attempt); delay < 0 || delay > policy.MaxDelay {
+				t.Errorf("strategy %d: Delay(%d) = %s, want within [0, %s]", strategy, attempt, delay, policy.MaxDelay)
+			}
+		}
+	}
+}
+
+func TestRetryWaitsOnClock(t *testing.T) {
+	clock := newFakeClock()
+	policy := RetryPolicy{
+		MaxRetries: 5,
+		BaseDelay:  100 * time.Millisecond,
+		MaxDelay:   time.Second,
+		Jitter:     NoJitter,
+		Clock:      clock,
+	}
+
+	calls := 0
+	err := Retry(context.Background(), policy, func() error {
+		calls++
+		if calls < 4 {
+			return errTest
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Retry returned %v", err)
+	}
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}
+	if got := clock.Sleeps(); !slices.Equal(got, want) {
+		t.Errorf("sleeps = %v, want %v", got, want)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	clock := newFakeClock()
+	policy := RetryPolicy{
+		MaxRetries: 2,
+		BaseDelay:  100 * time.Millisecond,
+		MaxDelay:   time.Second,
+		Jitter:     NoJitter,
+		Clock:      clock,
+	}
+
+	err := Retry(context.Background(), policy, func() error {
+		return errTest
+	})
+
+	exhausted := &RetryExhaustedError{}
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("Retry returned %T %v, want *RetryExhaustedError", err, err)
+	}
+	if exhausted.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3 for MaxRetries 2", exhausted.Attempts)
+	}
+	if exhausted.Elapsed != 300*time.Millisecond {
+		t.Errorf("Elapsed = %s, want 300ms", exhausted.Elapsed)
+	}
+	if !errors.Is(err, errTest) {
+		t.Errorf("errors.Is(err, errTest) = false")
+	}
+}
+
+func TestRetryMaxRetriesZero(t *testing.T) {
+	clock := newFakeClock()
+
+	calls := 0
+	err := Retry(context.Background(), RetryPolicy{MaxRetries: 0, Clock: clock}, func() error {
+		calls++
+		return errTest
+	})
+
+	if err != errTest {
+		t.Errorf("Retry returned %v, want the unwrapped error", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1 with MaxRetries 0", calls)
+	}
+	if sleeps := clock.Sleeps(); len(sleeps) != 0 {
+		t.Errorf("sleeps = %v, want none", sleeps)
+	}
+}
+
+func TestRetryPermanent(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), RetryPolicy{MaxRetries: 5, Clock: newFakeClock()}, func() error {
+		calls++
+		return Permanent(errTest)
+	})
+
+	if err != errTest {
+		t.Errorf("Retry returned %v, want the unwrapped error", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxElapsed time.Duration
+		calls      int
+	}{
+		{"after first attempt", 50 * time.Millisecond, 1},
+		{"after second attempt", 250 * time.Millisecond, 2},
+	}
+
+	for _, test := range tests {
+		policy := RetryPolicy{
+			MaxRetries: 10,
+			BaseDelay:  100 * time.Millisecond,
+			MaxDelay:   time.Second,
+			Jitter:     NoJitter,
+			MaxElapsed: test.maxElapsed,
+			Clock:      newFakeClock(),
+		}
+
+		calls := 0
+		err := Retry(context.Background(), policy, func() error {
+			calls++
+			return errTest
+		})
+
+		exhausted := &RetryExhaustedError{}
+		if !errors.As(err, &exhausted) {
+			t.Errorf("%s: Retry returned %T %v, want *RetryExhaustedError", test.name, err, err)
+		}
+		if calls != test.calls {
+			t.Errorf("%s: calls = %d, want %d", test.name, calls, test.calls)
+		}
+	}
+}
+
+func TestRetryContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	policy := RetryPolicy{
+		MaxRetries: 5,
+		BaseDelay:  time.Hour,
+		MaxDelay:   time.Hour,
+		Jitter:     NoJitter,
+	}
+
+	err := Retry(ctx, policy, func() error {
+		return &APIError{Status: "failed", Code: 1}
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("errors.Is(err, context.Canceled) = false for %v", err)
+	}
+	apiError := &APIError{}
+	if !errors.As(err, &apiError) {
+		t.Errorf("errors.As(err, *APIError) = false for %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package emi_transport
+
+import (
+	"context"
+	"sync"
+)
+
+// 转发队列的默认长度
+const defaultSinkQueueSize = 64
+
+// 事件接收端，例如消息队列的适配器
+type EventSink interface {
+	Publish(context.Context, RawEvent) error
+}
+
+// 把事件源的每个事件同时转发给 EventSink 的事件源
+//
+// 转发在独立的协程中进行，sink 的错误只会被记录；
+// 转发队列已满时丢弃该事件的转发，不会阻塞事件的正常投递。
+type SinkEventSource struct {
+	sync.Mutex
+
+	logger Logger
+
+	source EventSource
+	sink   EventSink
+
+	cancel context.CancelFunc
+}
+
+var _ EventSource = (*SinkEventSource)(nil)
+
+func NewSinkEventSource(logger Logger, source EventSource, sink EventSink) *SinkEventSource {
+	return &SinkEventSource{
+		logger: logger,
+
+		source: source,
+		sink:   sink,
+	}
+}
+
+// 开启
+func (s *SinkEventSource) Open(ctx context.Context) (chan RawEvent, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	in, err := s.source.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	publishCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	out := make(chan RawEvent)
+	queue := make(chan RawEvent, defaultSinkQueueSize)
+
+	go s.publish(publishCtx, queue)
+	go s.forward(publishCtx, in, out, queue)
+
+	return out, nil
+}
+
+// 关闭
+func (s *SinkEventSource) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+
+	return s.source.Close()
+}
+
+// ctx 在 Close 时被取消，此后不再向 out 发送事件，避免无人消费时协程泄漏
+func (s *SinkEventSource) forward(ctx context.Context, in chan RawEvent, out chan RawEvent, queue chan RawEvent) {
+	defer close(out)
+	defer close(queue)
+
+	for event := range in {
+		select {
+		case queue <- event:
+		default:
+			s.logger.Warnf("Sink queue is full, dropping event %s from sink", event.Type)
+		}
+
+		select {
+		case out <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *SinkEventSource) publish(ctx context.Context, queue chan RawEvent) {
+	for event := range queue {
+		if err := s.sink.Publish(ctx, event); err != nil {
+			s.logger.Errorf("Failed to publish event %s to sink: %v", event.Type, err)
+		}
+	}
+}
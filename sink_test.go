@@ -0,0 +1,120 @@
+package emi_transport
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 测试用的事件源，事件由测试通过 send 写入
+type chanEventSource struct {
+	sync.Mutex
+
+	events chan RawEvent
+	opens  int
+}
+
+var _ EventSource = (*chanEventSource)(nil)
+
+func (c *chanEventSource) Open(ctx context.Context) (chan RawEvent, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.events = make(chan RawEvent)
+	c.opens++
+	return c.events, nil
+}
+
+func (c *chanEventSource) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.events != nil {
+		close(c.events)
+		c.events = nil
+	}
+	return nil
+}
+
+func (c *chanEventSource) send(t *testing.T, event RawEvent) {
+	t.Helper()
+
+	c.Lock()
+	events := c.events
+	c.Unlock()
+
+	select {
+	case events <- event:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out sending an event to the source")
+	}
+}
+
+// 把收到的事件写入通道的 EventSink
+type chanEventSink struct {
+	events chan RawEvent
+	err    error
+}
+
+func (c *chanEventSink) Publish(ctx context.Context, event RawEvent) error {
+	c.events <- event
+	return c.err
+}
+
+func TestSinkEventSourcePublishes(t *testing.T) {
+	source := &chanEventSource{}
+	sink := &chanEventSink{events: make(chan RawEvent, 1), err: errTest}
+	logger, logs := newTestLogger()
+	s := NewSinkEventSource(logger, source, sink)
+
+	out, err := s.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer s.Close()
+
+	source.send(t, RawEvent{Type: "message_receive"})
+
+	if event := readEvent(t, out); event.Type != "message_receive" {
+		t.Errorf("delivered event = %s, want message_receive", event.Type)
+	}
+	if event := readEvent(t, sink.events); event.Type != "message_receive" {
+		t.Errorf("published event = %s, want message_receive", event.Type)
+	}
+
+	// sink 的错误只记录日志
+	eventually(t, "the publish error log", func() bool {
+		return strings.Contains(logs.String(), "Failed to publish event")
+	})
+}
+
+func TestSinkEventSourceCloseWithoutConsumer(t *testing.T) {
+	source := &chanEventSource{}
+	sink := &chanEventSink{events: make(chan RawEvent, 1)}
+	logger, _ := newTestLogger()
+	s := NewSinkEventSource(logger, source, sink)
+
+	out, err := s.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+
+	// 不消费 out，转发协程阻塞在发送上
+	source.send(t, RawEvent{Type: "e0"})
+
+	if err := s.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+	waitClosed(t, out)
+
+	if _, err := s.Open(context.Background()); err != nil {
+		t.Errorf("Open after Close returned %v", err)
+	}
+	s.Close()
+
+	if source.opens != 2 {
+		t.Errorf("source opened %d times, want 2", source.opens)
+	}
+}
@@ -0,0 +1,38 @@
+package emi_transport
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 存放 access token 的环境变量
+const AccessTokenEnv = "EMI_ACCESS_TOKEN"
+
+// 从环境变量 EMI_ACCESS_TOKEN 读取 access token，未设置时返回空字符串
+func AccessTokenFromEnv() string {
+	return strings.TrimSpace(os.Getenv(AccessTokenEnv))
+}
+
+// 从文件读取 access token，去除首尾的空白与换行，适用于容器挂载的密钥文件
+func ReadAccessTokenFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read access token file: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// 使用环境变量 EMI_ACCESS_TOKEN 中的 access token 创建 HttpClient
+func NewHttpClientFromEnv(logger Logger, restGateway string) *HttpClient {
+	return NewHttpClient(logger, restGateway, AccessTokenFromEnv())
+}
+
+// 使用文件中的 access token 创建 HttpClient
+func NewHttpClientFromTokenFile(logger Logger, restGateway string, path string) (*HttpClient, error) {
+	accessToken, err := ReadAccessTokenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewHttpClient(logger, restGateway, accessToken), nil
+}
@@ -0,0 +1,42 @@
+package emi_transport
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAccessTokenFromEnv(t *testing.T) {
+	t.Setenv(AccessTokenEnv, " env-token\n")
+
+	if token := AccessTokenFromEnv(); token != "env-token" {
+		t.Errorf("AccessTokenFromEnv = %q, want env-token", token)
+	}
+
+	logger, _ := newTestLogger()
+	if h := NewHttpClientFromEnv(logger, "http://127.0.0.1"); h.accessToken != "env-token" {
+		t.Errorf("accessToken = %q, want env-token", h.accessToken)
+	}
+}
+
+func TestReadAccessTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("file-token\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger, _ := newTestLogger()
+	h, err := NewHttpClientFromTokenFile(logger, "http://127.0.0.1", path)
+	if err != nil {
+		t.Fatalf("NewHttpClientFromTokenFile returned %v", err)
+	}
+	if h.accessToken != "file-token" {
+		t.Errorf("accessToken = %q, want file-token", h.accessToken)
+	}
+
+	if _, err := ReadAccessTokenFile(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadAccessTokenFile returned %v for a missing file", err)
+	}
+}
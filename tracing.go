@@ -0,0 +1,38 @@
+package emi_transport
+
+import "context"
+
+// 链路追踪接口，可以适配 OpenTelemetry 等实现，避免直接依赖具体的追踪库
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// 追踪中的一个区间
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// 不做任何事情的 Tracer（默认）
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, any) {}
+
+func (noopSpan) RecordError(error) {}
+
+func (noopSpan) End() {}
+
+// 设置 Tracer，每次 API 调用都会创建一个区间，为 nil 时不追踪
+func (h *HttpClient) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+	h.tracer = tracer
+}
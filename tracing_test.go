@@ -0,0 +1,72 @@
+package emi_transport
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type recordingTracer struct {
+	sync.Mutex
+
+	spans []*recordingSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	r.Lock()
+	defer r.Unlock()
+
+	span := &recordingSpan{name: name, attributes: map[string]any{}}
+	r.spans = append(r.spans, span)
+	return ctx, span
+}
+
+type recordingSpan struct {
+	name       string
+	attributes map[string]any
+	err        error
+	ended      bool
+}
+
+func (s *recordingSpan) SetAttribute(key string, value any) {
+	s.attributes[key] = value
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *recordingSpan) End() {
+	s.ended = true
+}
+
+func TestTracerSpanPerCall(t *testing.T) {
+	hits := atomic.Int32{}
+	h, _ := newTestHttpClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeResult(w, "ok", 0, map[string]any{})
+	})
+
+	tracer := &recordingTracer{}
+	h.SetTracer(tracer)
+
+	if err := h.Post(context.Background(), "get_login_info", nil, nil); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != "emi.http get_login_info" || !span.ended || span.err != nil {
+		t.Errorf("span = %+v", span)
+	}
+	if span.attributes["emi.attempts"] != 2 || span.attributes["emi.status"] != "ok" {
+		t.Errorf("attributes = %v", span.attributes)
+	}
+}
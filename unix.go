@@ -0,0 +1,50 @@
+package emi_transport
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// unix 套接字网关在 HTTP 层使用的主机名
+const unixSocketHost = "unix"
+
+// 解析 unix:// 形式的网关地址
+//
+// 例如 "unix:///var/run/milky.sock#/event" 的套接字路径为 "/var/run/milky.sock"，
+// fragment 部分 "/event" 作为 HTTP 请求路径（可省略）。
+func parseUnixGateway(gateway string) (socketPath string, requestPath string, ok bool) {
+	u, err := url.Parse(gateway)
+	if err != nil || u.Scheme != "unix" {
+		return "", "", false
+	}
+	return u.Host + u.Path, u.Fragment, true
+}
+
+// 拨号到 unix 套接字的函数
+func unixDialContext(socketPath string) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	dialer := net.Dialer{}
+	return func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", socketPath)
+	}
+}
+
+// 基于 base 构建拨号到 unix 套接字的 HTTP Transport，base 为 nil 时基于 http.DefaultTransport
+//
+// base 不是 *http.Transport（例如调用方的包装）时无法替换其拨号方式，原样返回 base 与 false，
+// 此时需要由 base 自行拨号到套接字。
+func unixTransport(socketPath string, base http.RoundTripper) (http.RoundTripper, bool) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return base, false
+	}
+
+	transport = transport.Clone()
+	transport.DialContext = unixDialContext(socketPath)
+	return transport, true
+}
@@ -0,0 +1,114 @@
+package emi_transport
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseUnixGateway(t *testing.T) {
+	tests := []struct {
+		gateway     string
+		socketPath  string
+		requestPath string
+		ok          bool
+	}{
+		{"unix:///var/run/milky.sock", "/var/run/milky.sock", "", true},
+		{"unix:///var/run/milky.sock#/event", "/var/run/milky.sock", "/event", true},
+		{"http://127.0.0.1:3000", "", "", false},
+		{"ws://127.0.0.1:3000/event", "", "", false},
+	}
+
+	for _, test := range tests {
+		socketPath, requestPath, ok := parseUnixGateway(test.gateway)
+		if socketPath != test.socketPath || requestPath != test.requestPath || ok != test.ok {
+			t.Errorf("parseUnixGateway(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				test.gateway, socketPath, requestPath, ok, test.socketPath, test.requestPath, test.ok)
+		}
+	}
+}
+
+func TestHttpClientOverUnixSocket(t *testing.T) {
+	// unix 套接字路径长度有限，不使用 t.TempDir 的长路径
+	dir, err := os.MkdirTemp("", "emi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "milky.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/get_login_info" {
+			t.Errorf("path = %s, want /get_login_info", r.URL.Path)
+		}
+		writeResult(w, "ok", 0, map[string]any{"nickname": "emi"})
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	logger, _ := newTestLogger()
+	h := NewHttpClient(logger, "unix://"+socketPath, "")
+
+	out := map[string]any{}
+	if err := h.Post(context.Background(), "get_login_info", nil, &out); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+	if out["nickname"] != "emi" {
+		t.Errorf("nickname = %v, want emi", out["nickname"])
+	}
+}
+
+// 记录调用次数的 RoundTripper 包装
+type countingRoundTripper struct {
+	base  http.RoundTripper
+	calls atomic.Int32
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.calls.Add(1)
+	return c.base.RoundTrip(req)
+}
+
+func TestHttpClientOverUnixSocketKeepsCustomTransport(t *testing.T) {
+	dir, err := os.MkdirTemp("", "emi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "milky.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, "ok", 0, map[string]any{})
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// 包装无法被替换拨号方式，由包装内的 Transport 自行拨号到套接字
+	transport := &countingRoundTripper{base: &http.Transport{DialContext: unixDialContext(socketPath)}}
+
+	logger, logs := newTestLogger()
+	h := NewHttpClientWithOptions(logger, "unix://"+socketPath, "", http.Client{Transport: transport}, 0, 0, 0, 0)
+
+	if err := h.Post(context.Background(), "get_login_info", nil, nil); err != nil {
+		t.Fatalf("Post returned %v", err)
+	}
+	if transport.calls.Load() != 1 {
+		t.Errorf("custom transport called %d times, want 1", transport.calls.Load())
+	}
+	if !strings.Contains(logs.String(), "Cannot dial unix socket") {
+		t.Errorf("no error logged for a transport that cannot be configured, got:\n%s", logs.String())
+	}
+}
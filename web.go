@@ -0,0 +1,76 @@
+package emi_transport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"strconv"
+
+	emi_core "github.com/aK1r4z/emi-core"
+)
+
+// 访问 QQ Web 接口时携带 CSRF Token 的查询参数名
+var csrfTokenQueryKeys = []string{"bkn", "g_tk"}
+
+// 访问 QQ Web 接口的客户端，请求会自动携带 Cookies 与 CSRF Token（bkn/g_tk）
+//
+// 用于 Milky API 未覆盖、但 QQ Web 接口提供的功能。
+type WebClient struct {
+	client http.Client
+
+	csrfToken string
+}
+
+// 使用 domain 的 Cookies 与 CSRF Token 构建 WebClient
+func (h *HttpClient) NewWebClient(ctx context.Context, domain string) (*WebClient, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+
+	if err := h.FillCookieJar(ctx, jar, domain); err != nil {
+		return nil, err
+	}
+
+	resp, err := h.GetCSRFToken(ctx, emi_core.GetCSRFTokenRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebClient{
+		client: http.Client{
+			Jar: jar,
+		},
+
+		csrfToken: resp.CSRFToken,
+	}, nil
+}
+
+// 当前使用的 CSRF Token
+func (c *WebClient) CSRFToken() string {
+	return c.csrfToken
+}
+
+// 发送请求，请求地址中没有 bkn/g_tk 参数时自动添加
+func (c *WebClient) Do(req *http.Request) (*http.Response, error) {
+	query := req.URL.Query()
+	for _, key := range csrfTokenQueryKeys {
+		if !query.Has(key) {
+			query.Set(key, c.csrfToken)
+		}
+	}
+	req.URL.RawQuery = query.Encode()
+
+	return c.client.Do(req)
+}
+
+// 由 skey（或 p_skey）计算 bkn/g_tk，与 QQ Web 页面使用的 hash33 算法相同
+func ComputeCSRFToken(skey string) string {
+	hash := int64(5381)
+	for _, char := range []byte(skey) {
+		// 与 JavaScript 一致，移位按 32 位有符号整数计算
+		hash += int64(int32(uint32(hash)<<5)) + int64(char)
+	}
+	return strconv.FormatInt(hash&0x7fffffff, 10)
+}
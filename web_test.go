@@ -0,0 +1,48 @@
+package emi_transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestComputeCSRFToken(t *testing.T) {
+	tests := map[string]string{
+		"":          "5381",
+		"abc":       "193485963",
+		"@AbCdEfGh": "691920905",
+	}
+
+	for skey, want := range tests {
+		if got := ComputeCSRFToken(skey); got != want {
+			t.Errorf("ComputeCSRFToken(%q) = %s, want %s", skey, got, want)
+		}
+	}
+}
+
+func TestWebClientAddsCSRFToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("bkn") != "123" || query.Get("g_tk") != "456" || query.Get("group") != "1" {
+			t.Errorf("query = %s, want bkn=123, the caller's g_tk and group", r.URL.RawQuery)
+		}
+	}))
+	defer server.Close()
+
+	c := &WebClient{csrfToken: "123"}
+
+	// 已有的参数不会被覆盖
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/list?group=1&g_tk=456", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Do returned %v", err)
+	}
+	resp.Body.Close()
+
+	if c.CSRFToken() != "123" {
+		t.Errorf("CSRFToken = %s, want 123", c.CSRFToken())
+	}
+}
@@ -6,16 +6,52 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/aK1r4z/emi-core"
+	emi_core "github.com/aK1r4z/emi-core"
 	"github.com/gorilla/websocket"
 )
 
 var ErrAlreadyConnected = errors.New("already connected")
 
+// 事件通道已满时的处理策略
+type BackpressurePolicy int
+
+const (
+	BackpressureBlock      BackpressurePolicy = 0 + iota // 阻塞等待消费者（默认）
+	BackpressureDropOldest                               // 丢弃最早的事件并记录警告
+)
+
+func (p BackpressurePolicy) String() string {
+	switch p {
+	case BackpressureBlock:
+		return "BLOCK"
+	case BackpressureDropOldest:
+		return "DROP_OLDEST"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// WebsocketEventSource 的健康状态
+type WebsocketHealth struct {
+	Connected   bool      // 当前是否已连接
+	LastEventAt time.Time // 最后一次收到事件的时间，尚未收到时为零值
+}
+
+// WebsocketEventSource 的统计快照
+type WebsocketStats struct {
+	Dropped int64 // 因通道已满被丢弃的事件数
+	Blocked int64 // 因通道已满而阻塞发送的次数
+}
+
 type WebsocketEventSource struct {
 	sync.RWMutex
 
@@ -23,54 +59,282 @@ type WebsocketEventSource struct {
 
 	wsGateway   string
 	accessToken string
+	auth        authConfig
+
+	dialer           *websocket.Dialer
+	subprotocols     []string
+	handshakeTimeout time.Duration
+	readTimeout      time.Duration
+
+	wsConn     *websocket.Conn
+	cancelOpen context.CancelFunc
+
+	eventBuffer  int
+	backpressure BackpressurePolicy
 
-	wsConn *websocket.Conn
+	allowTypes map[emi_core.EventType]struct{}
+	denyTypes  map[emi_core.EventType]struct{}
 
-	eventChan chan emi_core.RawEvent
+	dropped atomic.Int64
+	blocked atomic.Int64
+
+	selfID atomic.Int64
+
+	lastEventAt atomic.Int64
+
+	// 连接是否可用，重连等待期间为 false
+	connected atomic.Bool
+
+	maxOpenAttempts      int
+	maxReconnectAttempts int
+	reconnectBaseDelay   time.Duration
+	reconnectMaxDelay    time.Duration
+	reconnectHook        func(ReconnectInfo)
+	onReconnect          func()
+
+	clock Clock
+
+	eventChan chan RawEvent
 	closeChan chan any
 }
 
+var _ EventSource = (*WebsocketEventSource)(nil)
+
 func NewWebsocketEventSource(logger Logger, wsGateway string, accessToken string) *WebsocketEventSource {
 	return &WebsocketEventSource{
-		logger: logger,
+		logger: logger.Named("ws"),
 
 		wsGateway:   wsGateway,
 		accessToken: accessToken,
 
 		wsConn: nil,
 
+		reconnectBaseDelay: defaultReconnectBaseDelay,
+		reconnectMaxDelay:  defaultReconnectMaxDelay,
+
+		clock: realClock{},
+
 		eventChan: nil,
 		closeChan: nil,
 	}
 }
 
+// 设置 access token 的携带方式，scheme 仅在 AuthScheme 模式下使用
+func (w *WebsocketEventSource) SetAuthMode(mode AuthMode, scheme string) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.auth = authConfig{
+		mode:   mode,
+		scheme: scheme,
+	}
+}
+
+// 设置拨号使用的 Dialer，可用于配置代理、TLS 与缓冲区大小，为 nil 时使用 websocket.DefaultDialer
+//
+// Dialer 会被复制，SetSubprotocols 与 SetHandshakeTimeout 的设置优先于 Dialer 中的同名字段。
+// 在下一次连接时生效。
+func (w *WebsocketEventSource) SetDialer(dialer *websocket.Dialer) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.dialer = dialer
+}
+
+// 设置握手时请求的子协议，在下一次连接时生效
+func (w *WebsocketEventSource) SetSubprotocols(subprotocols ...string) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.subprotocols = append([]string(nil), subprotocols...)
+}
+
+// 设置握手超时，不大于 0 时使用 websocket.DefaultDialer 的默认值
+func (w *WebsocketEventSource) SetHandshakeTimeout(timeout time.Duration) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.handshakeTimeout = timeout
+}
+
+// 设置读取超时，超过该时长没有收到任何消息（包括 ping 与 pong）时视为连接中断，并进入重连流程
+//
+// 不大于 0 时不设置超时（默认）。在下一次连接时生效。
+func (w *WebsocketEventSource) SetReadTimeout(timeout time.Duration) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.readTimeout = timeout
+}
+
+// 当前连接协商得到的子协议，未连接或未协商时返回空字符串
+func (w *WebsocketEventSource) Subprotocol() string {
+	w.RLock()
+	defer w.RUnlock()
+
+	if w.wsConn == nil {
+		return ""
+	}
+	return w.wsConn.Subprotocol()
+}
+
+// 设置重连计时所使用的时钟，为 nil 时使用系统时间
+func (w *WebsocketEventSource) SetClock(clock Clock) {
+	w.Lock()
+	defer w.Unlock()
+
+	if clock == nil {
+		clock = realClock{}
+	}
+	w.clock = clock
+}
+
+// 设置事件通道的缓冲长度与通道已满时的处理策略，在下一次 Open 时生效
+func (w *WebsocketEventSource) SetEventBuffer(size int, policy BackpressurePolicy) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.eventBuffer = max(size, 0)
+	w.backpressure = policy
+}
+
+// 只投递指定类型的事件，不传参数时取消限制
+func (w *WebsocketEventSource) AllowEventTypes(types ...emi_core.EventType) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.allowTypes = eventTypeSet(types)
+}
+
+// 丢弃指定类型的事件，不传参数时取消限制；与 AllowEventTypes 同时设置时两者都需满足
+func (w *WebsocketEventSource) DenyEventTypes(types ...emi_core.EventType) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.denyTypes = eventTypeSet(types)
+}
+
+func eventTypeSet(types []emi_core.EventType) map[emi_core.EventType]struct{} {
+	if len(types) == 0 {
+		return nil
+	}
+
+	set := make(map[emi_core.EventType]struct{}, len(types))
+	for _, eventType := range types {
+		set[eventType] = struct{}{}
+	}
+	return set
+}
+
+// 判断事件类型是否通过过滤
+func (w *WebsocketEventSource) acceptEventType(eventType emi_core.EventType) bool {
+	w.RLock()
+	defer w.RUnlock()
+
+	if w.allowTypes != nil {
+		if _, ok := w.allowTypes[eventType]; !ok {
+			return false
+		}
+	}
+	_, denied := w.denyTypes[eventType]
+	return !denied
+}
+
+// 当前的背压处理策略
+func (w *WebsocketEventSource) BackpressurePolicy() BackpressurePolicy {
+	w.RLock()
+	defer w.RUnlock()
+
+	return w.backpressure
+}
+
+// 从收到的事件中得到的机器人 QQ 号，尚未收到事件时第二个返回值为 false
+func (w *WebsocketEventSource) SelfID() (int64, bool) {
+	selfID := w.selfID.Load()
+	return selfID, selfID != 0
+}
+
+// 获取健康状态，可用于 /healthz 等存活检查
+func (w *WebsocketEventSource) Health() WebsocketHealth {
+	health := WebsocketHealth{
+		Connected: w.connected.Load(),
+	}
+	if lastEventAt := w.lastEventAt.Load(); lastEventAt != 0 {
+		health.LastEventAt = time.Unix(0, lastEventAt)
+	}
+	return health
+}
+
+// 获取统计快照
+func (w *WebsocketEventSource) Stats() WebsocketStats {
+	return WebsocketStats{
+		Dropped: w.dropped.Load(),
+		Blocked: w.blocked.Load(),
+	}
+}
+
 func (w *WebsocketEventSource) Wait() {
 	<-w.closeChan
 }
 
+// 与 Wait 相同，但 ctx 被取消时提前返回
+//
+// 连接关闭时返回 nil，ctx 被取消时返回 ctx.Err()。
+func (w *WebsocketEventSource) WaitContext(ctx context.Context) error {
+	w.RLock()
+	closeChan := w.closeChan
+	w.RUnlock()
+
+	select {
+	case <-closeChan:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // 开启
-func (w *WebsocketEventSource) Open(ctx context.Context) (chan emi_core.RawEvent, error) {
+func (w *WebsocketEventSource) Open(ctx context.Context) (chan RawEvent, error) {
 	w.Lock()
-	defer w.Unlock()
 
-	if w.wsConn != nil {
+	if w.wsConn != nil || w.cancelOpen != nil {
+		w.Unlock()
 		return nil, ErrAlreadyConnected
 	}
 
-	dialer := websocket.DefaultDialer
-
-	header := http.Header{}
-	if w.accessToken != "" {
-		header.Add("Authorization", "Bearer "+w.accessToken)
+	dialer, gateway, header, err := w.dialConfig()
+	if err != nil {
+		w.Unlock()
+		return nil, err
 	}
 
-	wsConn, _, err := dialer.DialContext(ctx, w.wsGateway, header)
+	// 拨号与重试等待期间不持有锁，Close 可以取消正在进行的连接
+	openCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w.cancelOpen = cancel
+
+	w.Unlock()
+
+	wsConn, err := w.dialWithRetry(openCtx, dialer, gateway, header)
+
+	w.Lock()
+	defer w.Unlock()
+
+	w.cancelOpen = nil
+
 	if err != nil {
 		return nil, err
 	}
 
+	// 连接期间被 Close 取消
+	if openCtx.Err() != nil {
+		wsConn.Close()
+		return nil, ErrClosed
+	}
+
 	w.wsConn = wsConn
-	w.eventChan = make(chan emi_core.RawEvent)
+	w.connected.Store(true)
+	w.eventChan = make(chan RawEvent, w.eventBuffer)
 	w.closeChan = make(chan any)
 
 	go w.receive(wsConn, w.eventChan, w.closeChan)
@@ -78,33 +342,125 @@ func (w *WebsocketEventSource) Open(ctx context.Context) (chan emi_core.RawEvent
 	return w.eventChan, nil
 }
 
+// 构建拨号所需的 Dialer、地址与请求头，调用方需要持有锁
+func (w *WebsocketEventSource) dialConfig() (websocket.Dialer, string, http.Header, error) {
+	base := w.dialer
+	if base == nil {
+		base = websocket.DefaultDialer
+	}
+
+	dialer := *base
+	if w.subprotocols != nil {
+		dialer.Subprotocols = w.subprotocols
+	}
+	if w.handshakeTimeout > 0 {
+		dialer.HandshakeTimeout = w.handshakeTimeout
+	}
+	gateway := w.wsGateway
+
+	// unix 套接字网关
+	if socketPath, requestPath, ok := parseUnixGateway(gateway); ok {
+		dialer.NetDialContext = unixDialContext(socketPath)
+		gateway = (&url.URL{Scheme: "ws", Host: unixSocketHost, Path: requestPath}).String()
+	}
+
+	gatewayURL, err := url.Parse(gateway)
+	if err != nil {
+		return dialer, "", nil, fmt.Errorf("failed to parse websocket gateway: %w", err)
+	}
+
+	header := http.Header{}
+	w.auth.apply(header, gatewayURL, w.accessToken)
+
+	return dialer, gatewayURL.String(), header, nil
+}
+
+// 拨号，失败时返回 *WSHandshakeError
+func dialWebsocket(ctx context.Context, dialer websocket.Dialer, gateway string, header http.Header) (*websocket.Conn, error) {
+	wsConn, resp, err := dialer.DialContext(ctx, gateway, header)
+	if err == nil {
+		return wsConn, nil
+	}
+
+	handshakeErr := &WSHandshakeError{
+		Gateway: gateway,
+		Err:     err,
+	}
+	if gatewayURL, parseErr := url.Parse(gateway); parseErr == nil {
+		handshakeErr.Gateway = redactURL(gatewayURL)
+	}
+	if resp != nil {
+		handshakeErr.StatusCode = resp.StatusCode
+	}
+	return nil, handshakeErr
+}
+
 // 关闭
 func (w *WebsocketEventSource) Close() error {
 	w.Lock()
 	defer w.Unlock()
 
+	// 取消正在进行的 Open
+	if w.cancelOpen != nil {
+		w.cancelOpen()
+	}
+
 	if w.wsConn == nil {
 		return nil
 	}
 
+	// 事件通道由接收协程在退出时关闭，避免向已关闭的通道发送事件
 	err := w.wsConn.Close()
-	if err != nil {
-		return err
+	if errors.Is(err, net.ErrClosed) {
+		// 连接已在重连过程中关闭
+		err = nil
 	}
 
 	w.wsConn = nil
-	close(w.eventChan)
+	w.connected.Store(false)
 	close(w.closeChan)
 
-	return nil
+	return err
+}
+
+// 取出关闭后事件通道中剩余的事件
+//
+// 须在 Close 之后调用，连接仍在运行时返回 nil。事件按接收顺序返回，
+// 仅包含关闭前已进入通道缓冲的事件；也可以直接遍历 Open 返回的通道直到其关闭。
+func (w *WebsocketEventSource) Drain() []RawEvent {
+	w.RLock()
+	eventChan := w.eventChan
+	connected := w.wsConn != nil
+	w.RUnlock()
+
+	if eventChan == nil || connected {
+		return nil
+	}
+
+	events := []RawEvent{}
+	for event := range eventChan {
+		events = append(events, event)
+	}
+	return events
 }
 
 func (w *WebsocketEventSource) receive(
 	wsConn *websocket.Conn,
-	eventChan chan emi_core.RawEvent,
+	eventChan chan RawEvent,
 	closeChan chan any,
 ) {
+	defer close(eventChan)
+
+	w.RLock()
+	readTimeout := w.readTimeout
+	w.RUnlock()
+
+	watchReadTimeout(wsConn, readTimeout)
+
 	for {
+		if readTimeout > 0 {
+			wsConn.SetReadDeadline(time.Now().Add(readTimeout))
+		}
 		messageType, message, err := wsConn.ReadMessage()
 
 		// 在读取消息过程中出现错误
@@ -119,58 +475,149 @@ func (w *WebsocketEventSource) receive(
 				return
 			}
 
-			// [TODO] 如果连接仍在运行中，上报错误信息，然后尝试重连
+			// 如果连接仍在运行中，上报错误信息，然后尝试重连
 			w.logger.Errorf("Error when reading message: %v", err)
 
+			if newConn := w.reconnect(wsConn, err, closeChan); newConn != nil {
+				wsConn = newConn
+
+				// 新的连接使用最新的读取超时设置
+				w.RLock()
+				readTimeout = w.readTimeout
+				w.RUnlock()
+
+				watchReadTimeout(wsConn, readTimeout)
+				continue
+			}
+
 			err := w.Close()
 			if err != nil {
 				w.logger.Errorf("Failed to close websocket connection: %v", err)
 				// [TODO] 错误处理
 			}
 
-			return // [TODO] 重连
+			return
 		}
 
 		// 读取消息
 		messageBytes := message
 
-		// 如果消息是压缩的，使用 zlib 解压
-		if messageType == websocket.BinaryMessage {
-			zlib, err := zlib.NewReader(bytes.NewReader(message))
+		// 如果消息是 zlib 压缩的，解压；其余二进制消息按未压缩的 JSON 处理
+		if messageType == websocket.BinaryMessage && isZlib(message) {
+			messageBytes, err = decompressZlib(message)
 			if err != nil {
 				w.logger.Errorf("Failed to decompress message: %v", err)
-				// [TODO] 错误处理
-			}
-
-			messageBytes, err = io.ReadAll(zlib)
-			if err != nil {
-				w.logger.Errorf("Failed to read decompressed message: %v", err)
-				// [TODO] 错误处理
-			}
-
-			err = zlib.Close()
-			if err != nil {
-				w.logger.Errorf("Failed to close zlib reader: %v", err)
-				// [TODO] 错误处理
+				continue
 			}
+			w.logger.Tracef("Decompressed message from %d to %d bytes", len(message), len(messageBytes))
 		}
 
 		// 把事件解码为结构体
-		rawEvent := emi_core.RawEvent{}
+		rawEvent := RawEvent{}
 		if err = json.Unmarshal(messageBytes, &rawEvent); err != nil {
 			w.logger.Errorf("Failed to decode message: %v", err)
 			// [TODO] 错误处理
 		}
 		w.logger.Debugf("Received event: {event_type: %s, self_id: %d, time: %d, data: %s}", rawEvent.Type, rawEvent.SelfID, rawEvent.Time, rawEvent.Data)
 
-		// 发送事件
-		eventChan <- rawEvent
+		if rawEvent.SelfID != 0 {
+			w.selfID.Store(rawEvent.SelfID)
+		}
 
-		// 查看通道是否关闭
-		select {
-		case <-closeChan:
+		w.RLock()
+		w.lastEventAt.Store(w.clock.Now().UnixNano())
+		w.RUnlock()
+
+		// 丢弃被过滤的事件
+		if !w.acceptEventType(rawEvent.Type) {
+			continue
+		}
+
+		// 发送事件，连接关闭时停止
+		if !w.deliver(eventChan, closeChan, rawEvent) {
 			return
+		}
+	}
+}
+
+// 收到 ping 或 pong 时刷新读取超时，timeout 不大于 0 时不做处理
+func watchReadTimeout(wsConn *websocket.Conn, timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+
+	pingHandler := wsConn.PingHandler()
+	wsConn.SetPingHandler(func(appData string) error {
+		wsConn.SetReadDeadline(time.Now().Add(timeout))
+		return pingHandler(appData)
+	})
+
+	pongHandler := wsConn.PongHandler()
+	wsConn.SetPongHandler(func(appData string) error {
+		wsConn.SetReadDeadline(time.Now().Add(timeout))
+		return pongHandler(appData)
+	})
+}
+
+// 判断数据是否以 zlib 头开始
+func isZlib(data []byte) bool {
+	if len(data) < 2 {
+		return false
+	}
+	cmf, flg := data[0], data[1]
+	return cmf&0x0f == 8 && (uint16(cmf)<<8|uint16(flg))%31 == 0
+}
+
+// 使用 zlib 解压
+func decompressZlib(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// 按照背压策略发送事件，连接关闭时返回 false
+func (w *WebsocketEventSource) deliver(
+	eventChan chan RawEvent,
+	closeChan chan any,
+	event RawEvent,
+) bool {
+	select {
+	case eventChan <- event:
+		return true
+	default:
+	}
+
+	w.RLock()
+	policy := w.backpressure
+	w.RUnlock()
+
+	if policy == BackpressureDropOldest {
+		// 腾出最早的事件的位置，无缓冲的通道则直接丢弃当前事件
+		select {
+		case dropped := <-eventChan:
+			w.dropped.Add(1)
+			w.logger.Warnf("Event buffer is full, dropped oldest event %s", dropped.Type)
 		default:
 		}
+
+		select {
+		case eventChan <- event:
+		default:
+			w.dropped.Add(1)
+			w.logger.Warnf("Event buffer is full, dropped event %s", event.Type)
+		}
+		return true
+	}
+
+	w.blocked.Add(1)
+	select {
+	case eventChan <- event:
+		return true
+	case <-closeChan:
+		return false
 	}
 }
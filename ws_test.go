@@ -0,0 +1,484 @@
+package emi_transport
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	emi_core "github.com/aK1r4z/emi-core"
+	"github.com/gorilla/websocket"
+)
+
+// 测试用的 WebSocket 服务端，每个升级成功的连接都会发送到 conns
+type wsTestServer struct {
+	*httptest.Server
+
+	conns chan *websocket.Conn
+
+	// 拒绝前 reject 次握手
+	reject     atomic.Int32
+	handshakes atomic.Int32
+}
+
+func newWSTestServer(t *testing.T) *wsTestServer {
+	t.Helper()
+
+	s := &wsTestServer{
+		conns: make(chan *websocket.Conn, 8),
+	}
+
+	upgrader := websocket.Upgrader{Subprotocols: []string{"milky"}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handshakes.Add(1)
+		if s.reject.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		s.conns <- conn
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *wsTestServer) gateway() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+// 等待下一个连接
+func (s *wsTestServer) accept(t *testing.T) *websocket.Conn {
+	t.Helper()
+
+	select {
+	case conn := <-s.conns:
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a websocket connection")
+		return nil
+	}
+}
+
+func newTestWebsocketEventSource(gateway string) *WebsocketEventSource {
+	logger, _ := newTestLogger()
+	return NewWebsocketEventSource(logger, gateway, "secret-token")
+}
+
+func writeEvent(t *testing.T, conn *websocket.Conn, event RawEvent) {
+	t.Helper()
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		t.Fatalf("failed to write event: %v", err)
+	}
+}
+
+// 从通道读取一个事件
+func readEvent(t *testing.T, eventChan chan RawEvent) RawEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-eventChan:
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return RawEvent{}
+	}
+}
+
+// 等待通道关闭，返回等待的时长
+func waitClosed(t *testing.T, eventChan chan RawEvent) time.Duration {
+	t.Helper()
+
+	start := time.Now()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-eventChan:
+			if !ok {
+				return time.Since(start)
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the event channel to close")
+			return 0
+		}
+	}
+}
+
+// 轮询直到 cond 成立
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebsocketReceivesEvents(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	conn := server.accept(t)
+
+	if _, ok := w.SelfID(); ok {
+		t.Errorf("SelfID reported before any event")
+	}
+
+	writeEvent(t, conn, RawEvent{Type: "message_receive", SelfID: 10001, Time: 1700000000, Data: json.RawMessage(`{"a":1}`)})
+
+	event := readEvent(t, eventChan)
+	if event.Type != "message_receive" || event.Time != 1700000000 || string(event.Data) != `{"a":1}` {
+		t.Errorf("event = %+v", event)
+	}
+	if selfID, ok := w.SelfID(); !ok || selfID != 10001 {
+		t.Errorf("SelfID = %d, %v, want 10001", selfID, ok)
+	}
+}
+
+func TestWebsocketSubprotocol(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+	w.SetSubprotocols("onebot", "milky")
+
+	if _, err := w.Open(context.Background()); err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	server.accept(t)
+
+	if got := w.Subprotocol(); got != "milky" {
+		t.Errorf("Subprotocol = %q, want milky", got)
+	}
+}
+
+func TestWebsocketCloseIsIdempotent(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	server.accept(t)
+
+	if err := w.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close returned %v", err)
+	}
+	waitClosed(t, eventChan)
+}
+
+func TestWebsocketDropOldest(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+	w.SetEventBuffer(2, BackpressureDropOldest)
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	conn := server.accept(t)
+
+	// 不消费事件，只有最后两个事件保留在缓冲中
+	for _, eventType := range []emi_core.EventType{"e0", "e1", "e2", "e3", "e4"} {
+		writeEvent(t, conn, RawEvent{Type: eventType})
+	}
+	eventually(t, "3 dropped events", func() bool { return w.Stats().Dropped == 3 })
+
+	for _, want := range []emi_core.EventType{"e3", "e4"} {
+		if event := readEvent(t, eventChan); event.Type != want {
+			t.Errorf("event type = %s, want %s", event.Type, want)
+		}
+	}
+	if blocked := w.Stats().Blocked; blocked != 0 {
+		t.Errorf("Blocked = %d, want 0 with DropOldest", blocked)
+	}
+}
+
+func TestWebsocketBlockBackpressure(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	conn := server.accept(t)
+
+	writeEvent(t, conn, RawEvent{Type: "e0"})
+	writeEvent(t, conn, RawEvent{Type: "e1"})
+	eventually(t, "a blocked send", func() bool { return w.Stats().Blocked >= 1 })
+
+	for _, want := range []emi_core.EventType{"e0", "e1"} {
+		if event := readEvent(t, eventChan); event.Type != want {
+			t.Errorf("event type = %s, want %s", event.Type, want)
+		}
+	}
+	if dropped := w.Stats().Dropped; dropped != 0 {
+		t.Errorf("Dropped = %d, want 0", dropped)
+	}
+}
+
+func TestWebsocketDecodesZlibFrames(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	conn := server.accept(t)
+
+	compressed := bytes.Buffer{}
+	writer := zlib.NewWriter(&compressed)
+	writer.Write([]byte(`{"event_type":"compressed"}`))
+	writer.Close()
+
+	conn.WriteMessage(websocket.BinaryMessage, compressed.Bytes())
+	conn.WriteMessage(websocket.BinaryMessage, []byte(`{"event_type":"raw_binary"}`))
+
+	for _, want := range []emi_core.EventType{"compressed", "raw_binary"} {
+		if event := readEvent(t, eventChan); event.Type != want {
+			t.Errorf("event type = %s, want %s", event.Type, want)
+		}
+	}
+}
+
+func TestWebsocketDrain(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+	w.SetEventBuffer(4, BackpressureBlock)
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	conn := server.accept(t)
+
+	writeEvent(t, conn, RawEvent{Type: "e0"})
+	writeEvent(t, conn, RawEvent{Type: "e1"})
+	eventually(t, "2 buffered events", func() bool { return len(eventChan) == 2 })
+
+	if events := w.Drain(); events != nil {
+		t.Errorf("Drain returned %d events while connected, want nil", len(events))
+	}
+
+	w.Close()
+	events := w.Drain()
+	if len(events) != 2 || events[0].Type != "e0" || events[1].Type != "e1" {
+		t.Errorf("Drain = %+v, want e0 and e1", events)
+	}
+	if _, ok := <-eventChan; ok {
+		t.Errorf("event channel still open after Drain")
+	}
+}
+
+func TestWebsocketEventTypeFilter(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+	w.AllowEventTypes("message_receive", "friend_nudge")
+	w.DenyEventTypes("friend_nudge")
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	conn := server.accept(t)
+
+	for _, eventType := range []emi_core.EventType{"group_nudge", "friend_nudge", "message_receive"} {
+		writeEvent(t, conn, RawEvent{Type: eventType})
+	}
+
+	if event := readEvent(t, eventChan); event.Type != "message_receive" {
+		t.Errorf("first delivered event = %s, want message_receive", event.Type)
+	}
+}
+
+func TestWebsocketCustomDialer(t *testing.T) {
+	server := newWSTestServer(t)
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	dials := atomic.Int32{}
+	netDialer := net.Dialer{}
+	w := newTestWebsocketEventSource("ws://milky.invalid/event")
+	w.SetDialer(&websocket.Dialer{
+		NetDialContext: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			dials.Add(1)
+			return netDialer.DialContext(ctx, network, address)
+		},
+	})
+
+	if _, err := w.Open(context.Background()); err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	server.accept(t)
+
+	if dials.Load() != 1 {
+		t.Errorf("custom dialer used %d times, want 1", dials.Load())
+	}
+}
+
+func TestWebsocketWaitContext(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+
+	if _, err := w.Open(context.Background()); err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	server.accept(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.WaitContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitContext returned %v, want context.DeadlineExceeded", err)
+	}
+
+	w.Close()
+	if err := w.WaitContext(context.Background()); err != nil {
+		t.Errorf("WaitContext returned %v after Close, want nil", err)
+	}
+}
+
+func TestWebsocketHandshakeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	w := newTestWebsocketEventSource("ws" + strings.TrimPrefix(server.URL, "http"))
+	w.SetAuthMode(AuthQuery, "")
+
+	_, err := w.Open(context.Background())
+
+	handshakeErr := &WSHandshakeError{}
+	if !errors.As(err, &handshakeErr) {
+		t.Fatalf("Open returned %T %v, want *WSHandshakeError", err, err)
+	}
+	if handshakeErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("StatusCode = %d, want 401", handshakeErr.StatusCode)
+	}
+	if !errors.Is(err, websocket.ErrBadHandshake) {
+		t.Errorf("errors.Is(err, websocket.ErrBadHandshake) = false")
+	}
+	if strings.Contains(handshakeErr.Gateway, "secret-token") || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error leaks the access token: %v", err)
+	}
+}
+
+func TestWebsocketReadTimeout(t *testing.T) {
+	server := newWSTestServer(t)
+	w := newTestWebsocketEventSource(server.gateway())
+	w.SetReadTimeout(100 * time.Millisecond)
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	conn := server.accept(t)
+
+	// ping 刷新读取超时，停止发送后连接才会超时
+	go func() {
+		for i := 0; i < 10; i++ {
+			conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+			time.Sleep(30 * time.Millisecond)
+		}
+	}()
+
+	if elapsed := waitClosed(t, eventChan); elapsed < 250*time.Millisecond {
+		t.Errorf("connection timed out after %s while pings were being sent", elapsed)
+	}
+}
+
+func TestWebsocketHandshakeTimeout(t *testing.T) {
+	// 接受 TCP 连接但从不响应握手
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	w := newTestWebsocketEventSource("ws://" + listener.Addr().String())
+	w.SetHandshakeTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := w.Open(context.Background()); err == nil {
+		t.Fatal("Open succeeded without a handshake response")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Open took %s with a 50ms handshake timeout", elapsed)
+	}
+}
+
+func TestWebsocketLogsDecompressedSize(t *testing.T) {
+	server := newWSTestServer(t)
+	logger, logs := newTestLogger()
+	w := NewWebsocketEventSource(logger, server.gateway(), "secret-token")
+
+	eventChan, err := w.Open(context.Background())
+	if err != nil {
+		t.Fatalf("Open returned %v", err)
+	}
+	defer w.Close()
+	conn := server.accept(t)
+
+	message := []byte(`{"event_type":"compressed","data":"` + strings.Repeat("x", 1000) + `"}`)
+	compressed := bytes.Buffer{}
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(message)
+	writer.Close()
+
+	conn.WriteMessage(websocket.BinaryMessage, compressed.Bytes())
+	readEvent(t, eventChan)
+
+	want := fmt.Sprintf("Decompressed message from %d to %d bytes", compressed.Len(), len(message))
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("log does not contain %q, got:\n%s", want, logs.String())
+	}
+}